@@ -0,0 +1,183 @@
+package xdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// BatchInsert insert rows into table with the InsertRows of the dialect,
+// chunked to keep every statement under its MaxArgs and MaxRows. rows is a slice of
+// structs or pointers to structs mapped by the db tag, or a slice of maps such
+// as Rows, whose columns are the union of the keys. The chunks run in the
+// transaction of h when h is a TX, otherwise in a new one. return the number
+// of rows inserted
+func BatchInsert(h Helper, table string, rows interface{}) (int64, error) {
+	columns, args, err := batchArgs(rows)
+	if err != nil || len(args) == 0 {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("xdb: batch insert without column")
+	}
+
+	size, err := batchSize(h.Dialect(), len(columns))
+	if err != nil {
+		return 0, err
+	}
+
+	tx, inTx := h.(TX)
+	if !inTx {
+		db, ok := h.(DB)
+		if !ok {
+			return 0, fmt.Errorf("xdb: batch insert on %T, expect DB or TX", h)
+		}
+		if tx, err = db.Begin(); err != nil {
+			return 0, err
+		}
+		defer tx.Rollback()
+	}
+
+	var num int64
+	values := placeholders(len(columns))
+	for start := 0; start < len(args); start += size {
+		end := start + size
+		if end > len(args) {
+			end = len(args)
+		}
+		u := Upsert{Table: table, Columns: columns}
+		chunk := make([]interface{}, 0, (end-start)*len(columns))
+		for _, row := range args[start:end] {
+			u.Values = append(u.Values, values)
+			chunk = append(chunk, row...)
+		}
+		if _, err := tx.NewQuery().SQL(tx.Dialect().InsertRows(u)).Args(chunk...).Exec(); err != nil {
+			return num, err
+		}
+		num += int64(end - start)
+	}
+
+	if !inTx {
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	return num, nil
+}
+
+// batchSize rows of one chunk of columns, under the MaxArgs and the MaxRows
+// of d
+func batchSize(d Dialect, columns int) (int, error) {
+	size := d.MaxArgs() / columns
+	if size < 1 {
+		return 0, fmt.Errorf("xdb: batch insert %d columns, exceed max args %d", columns, d.MaxArgs())
+	}
+	if max := d.MaxRows(); max > 0 && size > max {
+		size = max
+	}
+	return size, nil
+}
+
+// batchArgs return the columns and the args of every row
+func batchArgs(rows interface{}) ([]string, [][]interface{}, error) {
+	val := reflect.Indirect(reflect.ValueOf(rows))
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, nil, fmt.Errorf("xdb: batch insert %T, expect slice", rows)
+	}
+	if val.Len() == 0 {
+		return nil, nil, nil
+	}
+
+	elType := val.Type().Elem()
+	switch {
+	case elType.Kind() == reflect.Map && elType.Key().Kind() == reflect.String:
+		return batchMapArgs(val)
+	case isStructType(elType):
+		return batchStructArgs(val, getStructInfo(elType))
+	}
+	return nil, nil, fmt.Errorf("xdb: batch insert %T, expect slice of struct or map", rows)
+}
+
+// batchStructArgs skip readonly fields and autoincr or omitempty fields zero
+// in every row, so the rows share the same columns
+func batchStructArgs(val reflect.Value, info *structInfo) ([]string, [][]interface{}, error) {
+	elems := make([]reflect.Value, val.Len())
+	for i := range elems {
+		elems[i] = reflect.Indirect(val.Index(i))
+		if !elems[i].IsValid() {
+			return nil, nil, fmt.Errorf("xdb: batch insert nil row %d", i)
+		}
+	}
+
+	var (
+		columns []string
+		fields  []*fieldInfo
+	)
+	for _, f := range info.fields {
+		if f.readonly {
+			continue
+		}
+		if f.autoIncr || f.omitEmpty {
+			zero := true
+			for _, elem := range elems {
+				if field := fieldByIndex(elem, f.index); field.IsValid() && !field.IsZero() {
+					zero = false
+					break
+				}
+			}
+			if zero {
+				continue
+			}
+		}
+		columns = append(columns, f.column)
+		fields = append(fields, f)
+	}
+
+	args := make([][]interface{}, len(elems))
+	for i, elem := range elems {
+		args[i] = make([]interface{}, len(fields))
+		for j, f := range fields {
+			if field := fieldByIndex(elem, f.index); field.IsValid() {
+				args[i][j] = field.Interface()
+			}
+		}
+	}
+	return columns, args, nil
+}
+
+// batchMapArgs bind missing keys as NULL and Value as its text
+func batchMapArgs(val reflect.Value) ([]string, [][]interface{}, error) {
+	set := map[string]bool{}
+	for i := 0; i < val.Len(); i++ {
+		for _, key := range val.Index(i).MapKeys() {
+			set[key.String()] = true
+		}
+	}
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	args := make([][]interface{}, val.Len())
+	for i := range args {
+		row := val.Index(i)
+		args[i] = make([]interface{}, len(columns))
+		for j, column := range columns {
+			v := row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key()))
+			if !v.IsValid() {
+				continue
+			}
+			switch arg := v.Interface().(type) {
+			case Value:
+				if arg != nil {
+					args[i][j] = string(arg)
+				}
+			default:
+				args[i][j] = arg
+			}
+		}
+	}
+	return columns, args, nil
+}
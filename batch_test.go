@@ -0,0 +1,111 @@
+package xdb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBatchInsert(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `batch` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `name` VARCHAR(64) NULL, `score` INTEGER NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `batch`").Exec()
+
+	log := LogFunc
+	LogFunc = nil
+	defer func() { LogFunc = log }()
+
+	type Entity struct {
+		ID    int64  `db:"id,pk,autoincr"`
+		Name  string `db:"name"`
+		Score int64  `db:"score,omitempty"`
+	}
+	es := make([]*Entity, 1200)
+	for i := range es {
+		es[i] = &Entity{Name: fmt.Sprintf("name-%d", i), Score: int64(i)}
+	}
+	n, err := BatchInsert(ndb, "batch", es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1200 {
+		t.Fatal("expect 1200, got", n)
+	}
+
+	tx, err := ndb.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = BatchInsert(tx, "batch", Rows{{"name": Value("a")}, {"name": Value("b"), "score": nil}, {"score": Value("7")}})
+	if err != nil || n != 3 {
+		t.Fatal("expect 3, got", n, err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err = NewRepository[Entity](ndb, "batch").InsertBatch([]*Entity{{Name: "x"}, {Name: "y"}})
+	if err != nil || n != 2 {
+		t.Fatal("expect 2, got", n, err)
+	}
+
+	val, err := ndb.NewQuery().Select("count(*)").From("batch").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Int() != 1202 {
+		t.Fatal("expect 1202, got", val)
+	}
+	row, err := ndb.NewQuery().Select("name, score").From("batch").Where("id = 1200").Row()
+	if err != nil || row.Get("name").String() != "name-1199" || row.Get("score").Int() != 1199 {
+		t.Fatal("unexpected row", row, err)
+	}
+
+	if _, err = BatchInsert(ndb, "batch", []int{1}); err == nil {
+		t.Fatal("expect error for slice of int")
+	}
+
+	q := newQuery(nil, newConfig(nil))
+	q.InsertInto("batch").Columns("name, score").ValuesRow("?, ?").ValuesRow("?, ?")
+	if expect := "INSERT INTO batch\n (name, score)\nVALUES (?, ?), (?, ?)"; q.String() != expect {
+		t.Fatalf("expect %q, got %q", expect, q.String())
+	}
+	q = newQuery(nil, newConfig(nil))
+	q.InsertInto("batch").Columns("name").Columns("score").Values("?").Values("?")
+	if expect := "INSERT INTO batch\n (name, score)\nVALUES (?, ?)"; q.String() != expect {
+		t.Fatalf("expect %q, got %q", expect, q.String())
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		columns int
+		expect  int
+	}{
+		{SQLite, 1, 999},
+		{SQLite, 10, 99},
+		{MySQL, 1, 65535},
+		{Postgres, 7, 9362},
+		{SQLServer, 1, 1000},
+		{SQLServer, 2, 1000},
+		{SQLServer, 3, 700},
+		{Oracle, 1, 1000},
+	}
+	for _, c := range cases {
+		if size, err := batchSize(c.dialect, c.columns); err != nil || size != c.expect {
+			t.Fatalf("%s %d columns: expect %d, got %d %v", c.dialect.Name(), c.columns, c.expect, size, err)
+		}
+	}
+	if _, err := batchSize(SQLServer, 2101); err == nil {
+		t.Fatal("expect error for too many columns")
+	}
+
+	u := Upsert{Table: "t", Columns: []string{"a", "b"}, Values: []string{"?, ?", "?, ?"}}
+	if s := SQLServer.InsertRows(u); s != "INSERT INTO t\n (a, b)\nVALUES (?, ?), (?, ?)" {
+		t.Fatal("unexpected sql", s)
+	}
+	if s := Oracle.InsertRows(u); s != "INSERT ALL\nINTO t (a, b) VALUES (?, ?)\nINTO t (a, b) VALUES (?, ?)\nSELECT 1 FROM dual" {
+		t.Fatal("unexpected sql", s)
+	}
+}
@@ -0,0 +1,112 @@
+package xdb
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CommentOptions sqlcommenter comment appended to every statement, so the
+// statements in the database logs tell where they come from, eg.
+//
+//	SELECT * FROM user /*application='api',caller='user.go%3A42',route='%2Fusers'*/
+type CommentOptions struct {
+	// Application tag, eg. the service name
+	Application string
+	// Caller tag the file:line of the caller outside xdb
+	Caller bool
+	// Tags more tags of ctx, eg. the traceparent of the span in ctx
+	Tags func(ctx context.Context) map[string]string
+}
+
+// WithComment append the sqlcommenter comment to the statements of the DB,
+// the sql of the Event and of LogFunc is without it
+func WithComment(opts CommentOptions) Option {
+	return func(cfg *config) {
+		cfg.comment = &opts
+	}
+}
+
+type commentKey struct{}
+
+// ContextWithComment ctx with tags commented on the statements run with it,
+// eg. the route of a request
+func ContextWithComment(ctx context.Context, tags map[string]string) context.Context {
+	merged := map[string]string{}
+	if parent, ok := ctx.Value(commentKey{}).(map[string]string); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, commentKey{}, merged)
+}
+
+func (c *CommentOptions) apply(ctx context.Context, rawSQL string) string {
+	tags := map[string]string{}
+	if c.Application != "" {
+		tags["application"] = c.Application
+	}
+	if c.Caller {
+		if caller := callerOutside(); caller != "" {
+			tags["caller"] = caller
+		}
+	}
+	if c.Tags != nil {
+		for k, v := range c.Tags(ctx) {
+			tags[k] = v
+		}
+	}
+	if ctxTags, ok := ctx.Value(commentKey{}).(map[string]string); ok {
+		for k, v := range ctxTags {
+			tags[k] = v
+		}
+	}
+	return appendComment(rawSQL, tags)
+}
+
+// appendComment append the tags as a sqlcommenter comment, keys sorted and
+// keys and values url encoded, before a trailing ; and not when rawSQL ends
+// with a comment already
+func appendComment(rawSQL string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return rawSQL
+	}
+	str := strings.TrimRight(rawSQL, " \t\r\n")
+	semicolon := strings.HasSuffix(str, ";")
+	str = strings.TrimSuffix(str, ";")
+	if strings.HasSuffix(str, "*/") {
+		return rawSQL
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buffer := new(strings.Builder)
+	buffer.WriteString(str)
+	buffer.WriteString(" /*")
+	for i, k := range keys {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString(commentEscape(k))
+		buffer.WriteString("='")
+		buffer.WriteString(commentEscape(tags[k]))
+		buffer.WriteString("'")
+	}
+	buffer.WriteString("*/")
+	if semicolon {
+		buffer.WriteString(";")
+	}
+	return buffer.String()
+}
+
+// commentEscape url encode str, quotes and comment delimiters included
+func commentEscape(str string) string {
+	return strings.ReplaceAll(url.QueryEscape(str), "+", "%20")
+}
@@ -0,0 +1,78 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+type recordingQuerier struct {
+	*sql.DB
+	sqls []string
+}
+
+func (r *recordingQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.sqls = append(r.sqls, query)
+	return r.DB.ExecContext(ctx, query, args...)
+}
+
+func (r *recordingQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.sqls = append(r.sqls, query)
+	return r.DB.QueryContext(ctx, query, args...)
+}
+
+func (r *recordingQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	r.sqls = append(r.sqls, query)
+	return r.DB.PrepareContext(ctx, query)
+}
+
+func TestAppendComment(t *testing.T) {
+	cases := []struct {
+		sql    string
+		tags   map[string]string
+		expect string
+	}{
+		{"select 1", map[string]string{"route": "/users/{id}", "application": "api"}, "select 1 /*application='api',route='%2Fusers%2F%7Bid%7D'*/"},
+		{"merge into t using s on (1 = 1);", map[string]string{"a": "it's */"}, "merge into t using s on (1 = 1) /*a='it%27s%20%2A%2F'*/;"},
+		{"select 1 /*app='x'*/", map[string]string{"a": "b"}, "select 1 /*app='x'*/"},
+		{"select 1", nil, "select 1"},
+	}
+	for _, c := range cases {
+		if s := appendComment(c.sql, c.tags); s != c.expect {
+			t.Fatalf("expect %q, got %q", c.expect, s)
+		}
+	}
+}
+
+func TestComment(t *testing.T) {
+	querier := &recordingQuerier{DB: db}
+	cfg := newConfig([]Option{WithComment(CommentOptions{Application: "api", Caller: true})})
+	ctx := ContextWithComment(context.Background(), map[string]string{"route": "/users"})
+
+	q := newQuery(querier, cfg)
+	if _, err := q.WithContext(ctx).Select("?").Args(1).Value(); err != nil {
+		t.Fatal(err)
+	}
+	if len(querier.sqls) != 1 || !strings.HasPrefix(querier.sqls[0], "SELECT ? /*application='api',caller='comment_test.go%3A") || !strings.HasSuffix(querier.sqls[0], ",route='%2Fusers'*/") {
+		t.Fatal("unexpected sql", querier.sqls)
+	}
+	if q.rawSQL != "SELECT ?" {
+		t.Fatal("unexpected raw sql", q.rawSQL)
+	}
+
+	querier.sqls = nil
+	p := newQuery(querier, cfg).Select("id").From("(select 1 as id union select 2) t").Where("id in (${Ids})")
+	defer p.Close()
+	if err := p.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	for _, ids := range [][]int{{1}, {1}, {1, 2}} {
+		if _, err := p.ReflectArgs(map[string]interface{}{"Ids": ids}).Rows(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(querier.sqls) != 2 || !strings.Contains(querier.sqls[0], "/*application='api'") || !strings.Contains(querier.sqls[1], "id in (?, ?)) /*application='api'") {
+		t.Fatal("unexpected prepared sql", querier.sqls)
+	}
+}
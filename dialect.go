@@ -0,0 +1,353 @@
+package xdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect sql dialect of the database behind a DB
+type Dialect interface {
+	// Name dialect name
+	Name() string
+	// Placeholder bind parameter for the index-th (1-based) argument
+	Placeholder(index int) string
+	// MaxArgs max bind parameters of one statement
+	MaxArgs() int
+	// MaxRows max rows of one multi-row insert, 0 for no limit
+	MaxRows() int
+	// InsertRows render the insert of every row of u.Values
+	InsertRows(u Upsert) string
+	// Upsert render the insert or update statement, an error when the
+	// dialect needs conflict columns and u has none
+	Upsert(u Upsert) (string, error)
+	// Savepoint create the savepoint name
+	Savepoint(name string) string
+	// ReleaseSavepoint release the savepoint name, empty when not supported
+	ReleaseSavepoint(name string) string
+	// RollbackSavepoint roll back to the savepoint name
+	RollbackSavepoint(name string) string
+	// Limit page clause reading size rows after skipping offset ones
+	Limit(size, offset int64) string
+	// InsertDefaults insert a row of default values into table, empty when
+	// not supported
+	InsertDefaults(table string) string
+	// Retryable report whether err is worth running the transaction again,
+	// eg. a deadlock or a serialization failure
+	Retryable(err error) bool
+}
+
+// Upsert insert statement updating the row conflicting on the unique key
+type Upsert struct {
+	Table   string
+	Columns []string
+	// Values one per row, without parentheses
+	Values []string
+	// Conflict columns of the unique key
+	Conflict []string
+	// Update columns overwritten by the inserted values, none for do nothing
+	Update []string
+}
+
+// Insert the plain insert statement
+func (u Upsert) Insert() string {
+	buffer := new(bytes.Buffer)
+	sqlClause(buffer, "INSERT INTO", []string{u.Table}, "", "", "")
+	sqlClause(buffer, "", u.Columns, "(", ")", ", ")
+	sqlClause(buffer, "VALUES", u.Values, "(", ")", "), (")
+	return buffer.String()
+}
+
+// insertAll render INSERT ALL of oracle, which has no multi-row VALUES
+func insertAll(u Upsert) string {
+	buffer := bytes.NewBufferString("INSERT ALL")
+	for _, values := range u.Values {
+		buffer.WriteString("\nINTO " + u.Table + " (" + strings.Join(u.Columns, ", ") + ") VALUES (" + values + ")")
+	}
+	buffer.WriteString("\nSELECT 1 FROM dual")
+	return buffer.String()
+}
+
+// errNoConflict upsert without the conflict columns the dialect needs
+var errNoConflict = errors.New("xdb: upsert needs the OnConflict columns")
+
+// onConflict render INSERT ... ON CONFLICT of postgres and sqlite, the
+// conflict columns are needed to do update
+func onConflict(u Upsert) (string, error) {
+	if len(u.Conflict) == 0 && len(u.Update) > 0 {
+		return "", errNoConflict
+	}
+	buffer := bytes.NewBufferString(u.Insert())
+	buffer.WriteString("\nON CONFLICT")
+	if len(u.Conflict) > 0 {
+		buffer.WriteString(" (" + strings.Join(u.Conflict, ", ") + ")")
+	}
+	if len(u.Update) == 0 {
+		buffer.WriteString(" DO NOTHING")
+		return buffer.String(), nil
+	}
+	buffer.WriteString(" DO UPDATE SET ")
+	for i, col := range u.Update {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(col + " = EXCLUDED." + col)
+	}
+	return buffer.String(), nil
+}
+
+// merge render MERGE of sqlserver and oracle, the rows are selected from
+// dual on oracle and from a VALUES table elsewhere, the conflict columns are
+// always needed
+func merge(u Upsert, dual bool) (string, error) {
+	if len(u.Conflict) == 0 {
+		return "", errNoConflict
+	}
+	as := " AS "
+	if dual {
+		as = " "
+	}
+	buffer := new(bytes.Buffer)
+	buffer.WriteString("MERGE INTO " + u.Table + as + "target\nUSING (")
+	if dual {
+		for i, values := range u.Values {
+			if i > 0 {
+				buffer.WriteString(" UNION ALL ")
+			}
+			buffer.WriteString("SELECT ")
+			for j, value := range splitSQLList(values) {
+				if j > 0 {
+					buffer.WriteString(", ")
+				}
+				if j < len(u.Columns) {
+					value += " " + u.Columns[j]
+				}
+				buffer.WriteString(value)
+			}
+			buffer.WriteString(" FROM dual")
+		}
+		buffer.WriteString(")" + as + "source")
+	} else {
+		buffer.WriteString("VALUES (" + strings.Join(u.Values, "), (") + "))" + as + "source (" + strings.Join(u.Columns, ", ") + ")")
+	}
+
+	buffer.WriteString("\nON (")
+	for i, col := range u.Conflict {
+		if i > 0 {
+			buffer.WriteString(" AND ")
+		}
+		buffer.WriteString("target." + col + " = source." + col)
+	}
+	buffer.WriteString(")")
+	if len(u.Update) > 0 {
+		buffer.WriteString("\nWHEN MATCHED THEN UPDATE SET ")
+		for i, col := range u.Update {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteString("target." + col + " = source." + col)
+		}
+	}
+	buffer.WriteString("\nWHEN NOT MATCHED THEN INSERT (" + strings.Join(u.Columns, ", ") + ") VALUES (")
+	for i, col := range u.Columns {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString("source." + col)
+	}
+	buffer.WriteString(")")
+	if !dual {
+		buffer.WriteString(";")
+	}
+	return buffer.String(), nil
+}
+
+// limitOffset render LIMIT n OFFSET m of mysql, postgres and sqlite
+func limitOffset(size, offset int64) string {
+	if offset == 0 {
+		return "LIMIT " + strconv.FormatInt(size, 10)
+	}
+	return fmt.Sprintf("LIMIT %d OFFSET %d", size, offset)
+}
+
+// offsetFetch render OFFSET m ROWS FETCH NEXT n ROWS ONLY of sqlserver and
+// oracle, sqlserver needs an ORDER BY before it
+func offsetFetch(size, offset int64) string {
+	return fmt.Sprintf("OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", offset, size)
+}
+
+// standardSQL savepoint, default values and multi-row insert statements of
+// the sql standard
+type standardSQL struct{}
+
+func (standardSQL) MaxRows() int               { return 0 }
+func (standardSQL) InsertRows(u Upsert) string { return u.Insert() }
+
+func (standardSQL) InsertDefaults(table string) string {
+	return "INSERT INTO " + table + " DEFAULT VALUES"
+}
+
+func (standardSQL) Savepoint(name string) string        { return "SAVEPOINT " + name }
+func (standardSQL) ReleaseSavepoint(name string) string { return "RELEASE SAVEPOINT " + name }
+func (standardSQL) RollbackSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+// splitSQLList split a comma separated list, commas inside parentheses and
+// quoted literals are kept
+func splitSQLList(str string) []string {
+	var (
+		items []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(str[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(str[start:]))
+}
+
+// built-in dialects
+var (
+	MySQL     Dialect = mysqlDialect{}
+	SQLite    Dialect = sqliteDialect{}
+	Postgres  Dialect = postgresDialect{}
+	SQLServer Dialect = sqlserverDialect{}
+	Oracle    Dialect = oracleDialect{}
+)
+
+// defaultDialect is used when New is called without WithDialect, it keeps
+// the "?" placeholders xdb always rendered
+var defaultDialect Dialect = genericDialect{}
+
+type genericDialect struct{ standardSQL }
+
+func (genericDialect) Name() string                    { return "default" }
+func (genericDialect) Placeholder(index int) string    { return "?" }
+func (genericDialect) MaxArgs() int                    { return 999 }
+func (genericDialect) Upsert(u Upsert) (string, error) { return onConflict(u) }
+func (genericDialect) Limit(size, offset int64) string { return limitOffset(size, offset) }
+
+// InsertDefaults of the default dialect is not supported, mysql has no
+// DEFAULT VALUES
+func (genericDialect) InsertDefaults(table string) string { return "" }
+
+// Retryable of the default dialect knows the errors of all the built-in
+// drivers it may be used with
+func (genericDialect) Retryable(err error) bool {
+	return mysqlRetryable(err) || postgresRetryable(err) || sqliteRetryable(err)
+}
+
+type mysqlDialect struct{ standardSQL }
+
+func (mysqlDialect) Name() string                 { return "mysql" }
+func (mysqlDialect) Placeholder(index int) string { return "?" }
+func (mysqlDialect) MaxArgs() int                 { return 65535 }
+func (mysqlDialect) Retryable(err error) bool     { return mysqlRetryable(err) }
+
+func (mysqlDialect) Limit(size, offset int64) string { return limitOffset(size, offset) }
+
+func (mysqlDialect) InsertDefaults(table string) string {
+	return "INSERT INTO " + table + " () VALUES ()"
+}
+
+// Upsert render ON DUPLICATE KEY UPDATE, do nothing assigns the first
+// conflict column to itself
+func (mysqlDialect) Upsert(u Upsert) (string, error) {
+	buffer := bytes.NewBufferString(u.Insert())
+	buffer.WriteString("\nON DUPLICATE KEY UPDATE ")
+	if len(u.Update) == 0 {
+		col := ""
+		if len(u.Conflict) > 0 {
+			col = u.Conflict[0]
+		} else if len(u.Columns) > 0 {
+			col = u.Columns[0]
+		}
+		buffer.WriteString(col + " = " + col)
+		return buffer.String(), nil
+	}
+	for i, col := range u.Update {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(col + " = VALUES(" + col + ")")
+	}
+	return buffer.String(), nil
+}
+
+type sqliteDialect struct{ standardSQL }
+
+func (sqliteDialect) Name() string                    { return "sqlite" }
+func (sqliteDialect) Placeholder(index int) string    { return "?" }
+func (sqliteDialect) MaxArgs() int                    { return 999 }
+func (sqliteDialect) Upsert(u Upsert) (string, error) { return onConflict(u) }
+func (sqliteDialect) Retryable(err error) bool        { return sqliteRetryable(err) }
+func (sqliteDialect) Limit(size, offset int64) string { return limitOffset(size, offset) }
+
+type postgresDialect struct{ standardSQL }
+
+func (postgresDialect) Name() string                    { return "postgres" }
+func (postgresDialect) Placeholder(index int) string    { return "$" + strconv.Itoa(index) }
+func (postgresDialect) MaxArgs() int                    { return 65535 }
+func (postgresDialect) Upsert(u Upsert) (string, error) { return onConflict(u) }
+func (postgresDialect) Retryable(err error) bool        { return postgresRetryable(err) }
+func (postgresDialect) Limit(size, offset int64) string { return limitOffset(size, offset) }
+
+type sqlserverDialect struct{}
+
+func (sqlserverDialect) Name() string                    { return "sqlserver" }
+func (sqlserverDialect) Placeholder(index int) string    { return "@p" + strconv.Itoa(index) }
+func (sqlserverDialect) MaxArgs() int                    { return 2100 }
+func (sqlserverDialect) Upsert(u Upsert) (string, error) { return merge(u, false) }
+func (sqlserverDialect) Limit(size, offset int64) string { return offsetFetch(size, offset) }
+func (sqlserverDialect) MaxRows() int                    { return 1000 }
+func (sqlserverDialect) InsertRows(u Upsert) string      { return u.Insert() }
+
+func (sqlserverDialect) InsertDefaults(table string) string {
+	return "INSERT INTO " + table + " DEFAULT VALUES"
+}
+
+func (sqlserverDialect) Savepoint(name string) string         { return "SAVE TRANSACTION " + name }
+func (sqlserverDialect) ReleaseSavepoint(name string) string  { return "" }
+func (sqlserverDialect) RollbackSavepoint(name string) string { return "ROLLBACK TRANSACTION " + name }
+
+// Retryable deadlock victim
+func (sqlserverDialect) Retryable(err error) bool { return hasErrorNumber(err, 1205) }
+
+// oracleDialect savepoints have no release, there is no insert of default
+// values nor multi-row VALUES
+type oracleDialect struct{ standardSQL }
+
+func (oracleDialect) Name() string                    { return "oracle" }
+func (oracleDialect) Placeholder(index int) string    { return ":" + strconv.Itoa(index) }
+func (oracleDialect) MaxArgs() int                    { return 65535 }
+func (oracleDialect) Upsert(u Upsert) (string, error) { return merge(u, true) }
+func (oracleDialect) Limit(size, offset int64) string { return offsetFetch(size, offset) }
+func (oracleDialect) MaxRows() int                    { return 1000 }
+func (oracleDialect) InsertRows(u Upsert) string      { return insertAll(u) }
+
+func (oracleDialect) ReleaseSavepoint(name string) string { return "" }
+func (oracleDialect) InsertDefaults(table string) string  { return "" }
+
+// Retryable deadlock and serialization failure
+func (oracleDialect) Retryable(err error) bool {
+	return hasErrorMessage(err, "ORA-00060", "ORA-08177")
+}
@@ -0,0 +1,196 @@
+package xdb
+
+import "testing"
+
+func TestDialectPlaceholder(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		sql     string
+		expect  string
+	}{
+		{defaultDialect, "select * from user where id = ${Id} and name = ?", "select * from user where id = ? and name = ?"},
+		{Postgres, "select * from user where id = ${Id} and name = ?", "select * from user where id = $1 and name = $2"},
+		{SQLServer, "select * from user where id = ? and name = ${Name}", "select * from user where id = @p1 and name = @p2"},
+		{Oracle, "select * from user where id = ? and name = ?", "select * from user where id = :1 and name = :2"},
+		{Postgres, "select '?', \"a?\" from user where id = ?", "select '?', \"a?\" from user where id = $1"},
+		{Postgres, "select * from user where name = '\\${Name}' and id = ${Id}", "select * from user where name = '${Name}' and id = $1"},
+	}
+	for _, c := range cases {
+		q := newQuery(nil, newConfig([]Option{WithDialect(c.dialect)}))
+		q.SQL(c.sql).(*query).build()
+		if q.rawSQL != c.expect {
+			t.Fatalf("%s: expect %q, got %q", c.dialect.Name(), c.expect, q.rawSQL)
+		}
+	}
+
+	q := newQuery(nil, newConfig([]Option{WithDialect(Postgres)}))
+	q.Update("user").Set("name = ?").Set("departname = ${Depart}").Where("id = ?")
+	q.build()
+	if expect := "UPDATE user\nSET name = $1, departname = $2\nWHERE (id = $3)"; q.rawSQL != expect {
+		t.Fatalf("expect %q, got %q", expect, q.rawSQL)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	build := func(dialect Dialect) *query {
+		q := newQuery(nil, newConfig([]Option{WithDialect(dialect)}))
+		q.InsertInto("user").Columns("id, username, departname").Values("${Id}, ${Name}, lower(${Depart})").OnConflict("id")
+		return q
+	}
+
+	cases := []struct {
+		dialect Dialect
+		expect  string
+	}{
+		{MySQL, "INSERT INTO user\n (id, username, departname)\nVALUES (?, ?, lower(?))\nON DUPLICATE KEY UPDATE username = VALUES(username), departname = VALUES(departname)"},
+		{Postgres, "INSERT INTO user\n (id, username, departname)\nVALUES ($1, $2, lower($3))\nON CONFLICT (id) DO UPDATE SET username = EXCLUDED.username, departname = EXCLUDED.departname"},
+		{SQLServer, "MERGE INTO user AS target\nUSING (VALUES (@p1, @p2, lower(@p3))) AS source (id, username, departname)\nON (target.id = source.id)\nWHEN MATCHED THEN UPDATE SET target.username = source.username, target.departname = source.departname\nWHEN NOT MATCHED THEN INSERT (id, username, departname) VALUES (source.id, source.username, source.departname);"},
+		{Oracle, "MERGE INTO user target\nUSING (SELECT :1 id, :2 username, lower(:3) departname FROM dual) source\nON (target.id = source.id)\nWHEN MATCHED THEN UPDATE SET target.username = source.username, target.departname = source.departname\nWHEN NOT MATCHED THEN INSERT (id, username, departname) VALUES (source.id, source.username, source.departname)"},
+	}
+	for _, c := range cases {
+		q := build(c.dialect)
+		q.build()
+		if q.rawSQL != c.expect {
+			t.Fatalf("%s: expect %q, got %q", c.dialect.Name(), c.expect, q.rawSQL)
+		}
+	}
+
+	q := build(MySQL)
+	q.DoNothing().(*query).build()
+	if expect := "INSERT INTO user\n (id, username, departname)\nVALUES (?, ?, lower(?))\nON DUPLICATE KEY UPDATE id = id"; q.rawSQL != expect {
+		t.Fatalf("expect %q, got %q", expect, q.rawSQL)
+	}
+	q = build(SQLite)
+	q.DoUpdate("departname").(*query).build()
+	if expect := "INSERT INTO user\n (id, username, departname)\nVALUES (?, ?, lower(?))\nON CONFLICT (id) DO UPDATE SET departname = EXCLUDED.departname"; q.rawSQL != expect {
+		t.Fatalf("expect %q, got %q", expect, q.rawSQL)
+	}
+
+	noConflict := func(dialect Dialect) *query {
+		q := newQuery(nil, newConfig([]Option{WithDialect(dialect)}))
+		q.InsertInto("user").Columns("id, username").Values("?, ?")
+		return q
+	}
+	for _, c := range []struct {
+		dialect Dialect
+		q       func(q *query) Query
+		err     bool
+	}{
+		{Postgres, func(q *query) Query { return q.DoUpdate("username") }, true},
+		{SQLite, func(q *query) Query { return q.DoUpdate("username") }, true},
+		{SQLServer, func(q *query) Query { return q.DoNothing() }, true},
+		{Oracle, func(q *query) Query { return q.DoUpdate("username") }, true},
+		{Postgres, func(q *query) Query { return q.DoNothing() }, false},
+		{MySQL, func(q *query) Query { return q.DoUpdate("username") }, false},
+	} {
+		_, _, err := c.q(noConflict(c.dialect)).(*query).bind()
+		if (err != nil) != c.err {
+			t.Fatalf("%s: expect error %v, got %v", c.dialect.Name(), c.err, err)
+		}
+	}
+
+	sdb := New(db, WithDialect(SQLite))
+	if _, err := sdb.NewQuery().SQL("CREATE TABLE `upsert` (`id` INTEGER PRIMARY KEY, `name` VARCHAR(64) NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer sdb.NewQuery().SQL("DROP TABLE `upsert`").Exec()
+	for _, name := range []string{"a", "b"} {
+		if _, err := sdb.NewQuery().InsertInto("upsert").Columns("id, name").Values("?, ?").Args(1, name).OnConflict("id").Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sdb.NewQuery().InsertInto("upsert").Columns("id, name").Values("?, ?").Args(1, "c").OnConflict("id").DoNothing().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	val, err := sdb.NewQuery().Select("name").From("upsert").Where("id = 1").Value()
+	if err != nil || val.String() != "b" {
+		t.Fatal("expect b, got", val, err)
+	}
+}
+
+func TestDialectSavepoint(t *testing.T) {
+	cases := []struct {
+		dialect                      Dialect
+		savepoint, release, rollback string
+	}{
+		{Postgres, "SAVEPOINT sp", "RELEASE SAVEPOINT sp", "ROLLBACK TO SAVEPOINT sp"},
+		{MySQL, "SAVEPOINT sp", "RELEASE SAVEPOINT sp", "ROLLBACK TO SAVEPOINT sp"},
+		{SQLServer, "SAVE TRANSACTION sp", "", "ROLLBACK TRANSACTION sp"},
+		{Oracle, "SAVEPOINT sp", "", "ROLLBACK TO SAVEPOINT sp"},
+	}
+	for _, c := range cases {
+		if c.dialect.Savepoint("sp") != c.savepoint || c.dialect.ReleaseSavepoint("sp") != c.release || c.dialect.RollbackSavepoint("sp") != c.rollback {
+			t.Fatalf("%s: unexpected savepoint statements", c.dialect.Name())
+		}
+	}
+}
+
+func TestDialectInsertDefaults(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		expect  string
+	}{
+		{Postgres, "INSERT INTO t DEFAULT VALUES"},
+		{SQLServer, "INSERT INTO t DEFAULT VALUES"},
+		{MySQL, "INSERT INTO t () VALUES ()"},
+		{Oracle, ""},
+		{defaultDialect, ""},
+	}
+	for _, c := range cases {
+		if s := c.dialect.InsertDefaults("t"); s != c.expect {
+			t.Fatalf("%s: expect %q, got %q", c.dialect.Name(), c.expect, s)
+		}
+	}
+}
+
+func TestDialectLimit(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		expect  string
+	}{
+		{Postgres, "LIMIT 10 OFFSET 20"},
+		{MySQL, "LIMIT 10 OFFSET 20"},
+		{SQLite, "LIMIT 10 OFFSET 20"},
+		{SQLServer, "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{Oracle, "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+	}
+	for _, c := range cases {
+		if s := c.dialect.Limit(10, 20); s != c.expect {
+			t.Fatalf("%s: expect %q, got %q", c.dialect.Name(), c.expect, s)
+		}
+		q := newQuery(nil, newConfig([]Option{WithDialect(c.dialect)}))
+		q.Select("id").From("t").OrderBy("id").Limit("5")
+		rawSQL, _, err := q.pageQuery(10, 20).bind()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := "SELECT id\nFROM t\nORDER BY id\n" + c.expect; rawSQL != expect {
+			t.Fatalf("%s: expect %q, got %q", c.dialect.Name(), expect, rawSQL)
+		}
+	}
+	if s := MySQL.Limit(10, 0); s != "LIMIT 10" {
+		t.Fatal("expect LIMIT 10, got", s)
+	}
+
+	q := newQuery(nil, newConfig([]Option{WithDialect(SQLServer)}))
+	q.Select("id").From("t")
+	if rawSQL, _, _ := q.pageQuery(10, 20).bind(); rawSQL != "SELECT id\nFROM t\nORDER BY (SELECT NULL)\nOFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY" {
+		t.Fatal("unexpected sql", rawSQL)
+	}
+	q = newQuery(nil, newConfig([]Option{WithDialect(SQLServer)}))
+	q.SQL("select top 100 id from t order by id")
+	if rawSQL, _, _ := q.pageQuery(10, 20).bind(); rawSQL != "SELECT * FROM (\nselect top 100 id from t order by id\n) t\nORDER BY (SELECT NULL)\nOFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY" {
+		t.Fatal("unexpected sql", rawSQL)
+	}
+	q = newQuery(nil, newConfig([]Option{WithDialect(SQLServer)}))
+	q.SQL("select id from t order by id;")
+	if rawSQL, _, _ := q.pageQuery(10, 20).bind(); rawSQL != "select id from t order by id\nOFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY" {
+		t.Fatal("unexpected sql", rawSQL)
+	}
+	q = newQuery(nil, newConfig([]Option{WithDialect(Postgres)}))
+	q.SQL("select id from t where id > ? order by id limit ?").Args(1, 100)
+	rawSQL, args, _ := q.pageQuery(10, 20).bind()
+	if rawSQL != "SELECT * FROM (\nselect id from t where id > $1 order by id limit $2\n) t\nLIMIT 10 OFFSET 20" || len(args) != 2 {
+		t.Fatal("unexpected sql", rawSQL, args)
+	}
+}
@@ -0,0 +1,215 @@
+package xdb
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Get first row of q as T, sql.ErrNoRows when empty. T is a struct (or
+// pointer to struct) mapped by the db tag, or a scalar for a single column
+func Get[T any](q Query) (T, error) {
+	var row T
+	it, err := iterate(q)
+	if err != nil {
+		return row, err
+	}
+	defer it.Close()
+
+	scan, err := newTypedScanner[T](it, -1)
+	if err != nil {
+		return row, err
+	}
+	if !it.Next() {
+		if err := it.Err(); err != nil {
+			return row, err
+		}
+		return row, sql.ErrNoRows
+	}
+	if err := scan(&row); err != nil {
+		return row, err
+	}
+	return row, nil
+}
+
+// Select all rows of q as []T, T as in Get
+func Select[T any](q Query) ([]T, error) {
+	it, err := iterate(q)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	scan, err := newTypedScanner[T](it, -1)
+	if err != nil {
+		return nil, err
+	}
+	rows := []T{}
+	for it.Next() {
+		var row T
+		if err := scan(&row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, it.Err()
+}
+
+// Pluck column of all rows of q as []T, T is a scalar
+func Pluck[T any](q Query, column string) ([]T, error) {
+	it, err := iterate(q)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	index := -1
+	for i, col := range it.columns {
+		if col == column {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("column %s not exist", column)
+	}
+	if isStructType(reflect.TypeOf((*T)(nil)).Elem()) {
+		return nil, fmt.Errorf("xdb: can't pluck column %s into struct", column)
+	}
+
+	scan, err := newTypedScanner[T](it, index)
+	if err != nil {
+		return nil, err
+	}
+	vals := []T{}
+	for it.Next() {
+		var val T
+		if err := scan(&val); err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	return vals, it.Err()
+}
+
+// iterate q with the row scanner of xdb, an Iterator of another Query
+// implementation is an error
+func iterate(q Query) (*iterator, error) {
+	it, err := q.Iterate()
+	if err != nil {
+		return nil, err
+	}
+	xit, ok := it.(*iterator)
+	if !ok {
+		it.Close()
+		return nil, fmt.Errorf("xdb: iterator %T of %T, expect a query of xdb", it, q)
+	}
+	return xit, nil
+}
+
+// newTypedScanner return the func scanning the current row into *T, a struct
+// T maps every column, a scalar T the column at index, the only column when
+// index is -1
+func newTypedScanner[T any](it *iterator, index int) (func(*T) error, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if isStructType(typ) {
+		return func(dest *T) error {
+			val := reflect.ValueOf(dest).Elem()
+			if val.Kind() == reflect.Ptr {
+				val.Set(reflect.New(val.Type().Elem()))
+				val = val.Elem()
+			}
+			if err := it.scan(); err != nil {
+				return err
+			}
+			return it.setStruct(val)
+		}, nil
+	}
+
+	if index == -1 {
+		if len(it.columns) != 1 {
+			return nil, fmt.Errorf("xdb: scan %d columns into %s, expect 1", len(it.columns), typ)
+		}
+		index = 0
+	}
+	return func(dest *T) error {
+		if err := it.scan(); err != nil {
+			return err
+		}
+		if err := setScalarValue(reflect.ValueOf(dest).Elem(), Value(it.vals[index])); err != nil {
+			return fmt.Errorf("xdb: scan column %s: %v", it.columns[index], err)
+		}
+		return nil
+	}, nil
+}
+
+// setScalarValue set val from v like setFieldValue, returning the parse
+// errors of the E accessors, NULL is the zero value or a nil pointer
+func setScalarValue(val reflect.Value, v Value) error {
+	if val.Kind() == reflect.Ptr {
+		if v == nil {
+			val.Set(reflect.Zero(val.Type()))
+			return nil
+		}
+		if val.IsNil() {
+			val.Set(reflect.New(val.Type().Elem()))
+		}
+		return setScalarValue(val.Elem(), v)
+	}
+	if v == nil || val.Addr().Type().Implements(scannerType) {
+		return setFieldValue(val, sql.RawBytes(v))
+	}
+	switch val.Kind() {
+	case reflect.Bool:
+		b, err := v.BoolE()
+		if err != nil {
+			return err
+		}
+		val.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := v.IntE()
+		if err != nil {
+			return err
+		}
+		if val.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, val.Type())
+		}
+		val.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := v.UintE()
+		if err != nil {
+			return err
+		}
+		if val.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, val.Type())
+		}
+		val.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := v.FloatE()
+		if err != nil {
+			return err
+		}
+		val.SetFloat(f)
+	case reflect.Struct:
+		if val.Type() != timeType {
+			return setFieldValue(val, sql.RawBytes(v))
+		}
+		t, err := v.TimeE()
+		if err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(t))
+	default:
+		return setFieldValue(val, sql.RawBytes(v))
+	}
+	return nil
+}
+
+// isStructType report whether typ is mapped field by field, time.Time and
+// sql.Scanner structs are scalars
+func isStructType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType && !reflect.PtrTo(typ).Implements(scannerType)
+}
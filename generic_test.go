@@ -0,0 +1,106 @@
+package xdb
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestGeneric(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `generic` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `name` VARCHAR(64) NULL, `created` STRING NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `generic`").Exec()
+	for _, name := range []interface{}{"a", "b", nil} {
+		if _, err := ndb.NewQuery().InsertInto("generic").Columns("name, created").Values("?, '2017-10-01 08:00:00'").Args(name).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type Entity struct {
+		ID   int64          `db:"id"`
+		Name sql.NullString `db:"name"`
+	}
+
+	e, err := Get[Entity](ndb.NewQuery().Select("id, name").From("generic").Where("id = ?").Args(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != 2 || e.Name.String != "b" {
+		t.Fatalf("unexpected entity %+v", e)
+	}
+
+	if _, err = Get[*Entity](ndb.NewQuery().Select("id").From("generic").Where("id = ?").Args(100)); err != sql.ErrNoRows {
+		t.Fatal("expect sql.ErrNoRows, got", err)
+	}
+
+	es, err := Select[*Entity](ndb.NewQuery().Select("id, name").From("generic").OrderBy("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 3 || es[2].ID != 3 || es[2].Name.Valid {
+		t.Fatalf("unexpected entities %+v", es)
+	}
+
+	count, err := Get[int64](ndb.NewQuery().Select("count(*)").From("generic"))
+	if err != nil || count != 3 {
+		t.Fatal("expect 3, got", count, err)
+	}
+
+	created, err := Get[time.Time](ndb.NewQuery().Select("created").From("generic"))
+	if err != nil || created.Year() != 2017 {
+		t.Fatal("expect 2017, got", created, err)
+	}
+
+	if _, err = Get[int64](ndb.NewQuery().Select("'abc'")); err == nil {
+		t.Fatal("expect error for int64 of abc")
+	}
+	if _, err = Get[time.Time](ndb.NewQuery().Select("'abc'")); err == nil {
+		t.Fatal("expect error for time of abc")
+	}
+	if _, err = Get[int8](ndb.NewQuery().Select("1000")); err == nil {
+		t.Fatal("expect error for int8 overflow")
+	}
+
+	if _, err = Select[string](ndb.NewQuery().Select("id, name").From("generic")); err == nil {
+		t.Fatal("expect error for multiple columns")
+	}
+
+	names, err := Pluck[*string](ndb.NewQuery().Select("id, name").From("generic").OrderBy("id"), "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 || *names[0] != "a" || names[2] != nil {
+		t.Fatal("unexpected names", names)
+	}
+
+	ids, err := Pluck[int](ndb.NewQuery().Select("id, name").From("generic").OrderBy("id"), "id")
+	if err != nil || len(ids) != 3 || ids[1] != 2 {
+		t.Fatal("unexpected ids", ids, err)
+	}
+
+	if _, err = Pluck[int](ndb.NewQuery().Select("id").From("generic"), "none"); err == nil {
+		t.Fatal("expect error for missing column")
+	}
+
+	if _, err = Select[int](wrappedQuery{ndb.NewQuery().Select("id").From("generic")}); err == nil {
+		t.Fatal("expect error for wrapped iterator")
+	}
+}
+
+// wrappedQuery Query whose Iterator is not the one of xdb
+type wrappedQuery struct {
+	Query
+}
+
+type wrappedIterator struct {
+	Iterator
+}
+
+func (w wrappedQuery) Iterate() (Iterator, error) {
+	it, err := w.Query.Iterate()
+	if err != nil {
+		return nil, err
+	}
+	return wrappedIterator{it}, nil
+}
@@ -0,0 +1,200 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Op operation of an Event
+type Op string
+
+// intercepted operations
+const (
+	OpExec     Op = "exec"
+	OpQuery    Op = "query"
+	OpPrepare  Op = "prepare"
+	OpBegin    Op = "begin"
+	OpCommit   Op = "commit"
+	OpRollback Op = "rollback"
+)
+
+// Event of an intercepted operation, Duration, RowsAffected, RowsRead and
+// Err are set after the operation. A query lasts until its rows are read to
+// the end or closed, After is called then
+type Event struct {
+	Op Op
+	// Statement insert, delete, update or select of a builder query, the
+	// first keyword of SQL in lower case otherwise
+	Statement string
+	// SQL the statement, or the savepoint statement of a nested TX, empty
+	// when the dialect has none
+	SQL string
+	// Savepoint name of the savepoint of a nested TX begin, commit or
+	// rollback, empty otherwise
+	Savepoint string
+	Args      []interface{}
+	// Attempt run of the statement or of its transaction, 1 unless retried
+	Attempt  int
+	Start    time.Time
+	Duration time.Duration
+	// RowsAffected of an exec, -1 otherwise or when the driver can't tell
+	RowsAffected int64
+	// RowsRead rows read from a query, -1 otherwise
+	RowsRead int64
+	Err      error
+}
+
+// Interceptor hooks around every exec, query, prepare, begin, commit and
+// rollback of a DB and its TX
+type Interceptor interface {
+	// Before is called before the operation, the context returned is passed
+	// on to the next Before, the driver and After
+	Before(ctx context.Context, e *Event) context.Context
+	// After is called after the operation, in reverse order of Before
+	After(ctx context.Context, e *Event)
+}
+
+// InterceptorFuncs Interceptor of funcs, a nil func is skipped
+type InterceptorFuncs struct {
+	BeforeFunc func(ctx context.Context, e *Event) context.Context
+	AfterFunc  func(ctx context.Context, e *Event)
+}
+
+// Before call BeforeFunc
+func (f InterceptorFuncs) Before(ctx context.Context, e *Event) context.Context {
+	if f.BeforeFunc == nil {
+		return ctx
+	}
+	return f.BeforeFunc(ctx, e)
+}
+
+// After call AfterFunc
+func (f InterceptorFuncs) After(ctx context.Context, e *Event) {
+	if f.AfterFunc != nil {
+		f.AfterFunc(ctx, e)
+	}
+}
+
+// WithInterceptor append interceptors to the chain of the DB, the first is
+// the outermost
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(cfg *config) {
+		for _, interceptor := range interceptors {
+			if interceptor != nil {
+				cfg.interceptors = append(cfg.interceptors, interceptor)
+			}
+		}
+	}
+}
+
+type attemptKey struct{}
+
+// withAttempt ctx of the attempt-th run of a retried operation
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+func attemptOf(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// intercept run fn inside the interceptor chain of cfg
+func intercept(ctx context.Context, cfg *config, e *Event, fn func(ctx context.Context) error) error {
+	ctx, end := startIntercept(ctx, cfg, e)
+	return end(fn(ctx))
+}
+
+// startIntercept call Before of the interceptor chain of cfg, the func
+// returned ends the operation with its error and calls After
+func startIntercept(ctx context.Context, cfg *config, e *Event) (context.Context, func(err error) error) {
+	if e.Statement == "" {
+		e.Statement = statementName(e.SQL)
+	}
+	e.Attempt = attemptOf(ctx)
+	e.RowsAffected, e.RowsRead = -1, -1
+	var interceptors []Interceptor
+	if cfg != nil {
+		interceptors = cfg.interceptors
+	}
+	for _, interceptor := range interceptors {
+		ctx = interceptor.Before(ctx, e)
+	}
+	e.Start = time.Now()
+	return ctx, func(err error) error {
+		e.Err = err
+		e.Duration = time.Since(e.Start)
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptors[i].After(ctx, e)
+		}
+		return err
+	}
+}
+
+// interceptExec intercept an exec, setting RowsAffected from its result
+func interceptExec(ctx context.Context, cfg *config, e *Event, fn func(ctx context.Context) (sql.Result, error)) (sql.Result, error) {
+	var result sql.Result
+	err := intercept(ctx, cfg, e, func(ctx context.Context) error {
+		var err error
+		if result, err = fn(ctx); err == nil && result != nil {
+			if n, err := result.RowsAffected(); err == nil {
+				e.RowsAffected = n
+			}
+		}
+		return err
+	})
+	return result, err
+}
+
+// interceptedRows rows of an intercepted query, counting the rows read and
+// ending the query when read to the end or closed
+type interceptedRows struct {
+	*sql.Rows
+	e    *Event
+	end  func(err error) error
+	read int64
+}
+
+func (r *interceptedRows) Next() bool {
+	if !r.Rows.Next() {
+		r.finish(r.Rows.Err())
+		return false
+	}
+	r.read++
+	return true
+}
+
+// Close close the rows and end the query if not ended yet
+func (r *interceptedRows) Close() error {
+	err := r.Rows.Close()
+	if iterErr := r.Rows.Err(); iterErr != nil {
+		r.finish(iterErr)
+	} else {
+		r.finish(err)
+	}
+	return err
+}
+
+// finish end the query once with err
+func (r *interceptedRows) finish(err error) {
+	if r.end == nil {
+		return
+	}
+	end := r.end
+	r.end = nil
+	r.e.RowsRead = r.read
+	end(err)
+}
+
+func statementName(sql string) string {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+	end := strings.IndexAny(sql, " \t\r\n(")
+	if end == -1 {
+		end = len(sql)
+	}
+	return strings.ToLower(sql[:end])
+}
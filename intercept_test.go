@@ -0,0 +1,71 @@
+package xdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type recordKey struct{}
+
+type recorder struct {
+	name   string
+	calls  *[]string
+	events []Event
+}
+
+func (r *recorder) Before(ctx context.Context, e *Event) context.Context {
+	*r.calls = append(*r.calls, "before "+r.name)
+	return context.WithValue(ctx, recordKey{}, r.name)
+}
+
+func (r *recorder) After(ctx context.Context, e *Event) {
+	*r.calls = append(*r.calls, "after "+r.name+" "+ctx.Value(recordKey{}).(string))
+	r.events = append(r.events, *e)
+}
+
+func TestInterceptor(t *testing.T) {
+	var calls []string
+	outer, inner := &recorder{name: "outer", calls: &calls}, &recorder{name: "inner", calls: &calls}
+	idb := New(db, WithInterceptor(outer, inner))
+
+	if _, err := idb.NewQuery().SQL("CREATE TABLE `intercept` (`id` INTEGER PRIMARY KEY, `name` VARCHAR(64) NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer idb.NewQuery().SQL("DROP TABLE `intercept`").Exec()
+	if strings.Join(calls, ", ") != "before outer, before inner, after inner inner, after outer inner" {
+		t.Fatal("unexpected chain order", calls)
+	}
+
+	err := idb.Transact(context.Background(), nil, func(tx TX) error {
+		_, err := tx.NewQuery().InsertInto("intercept").Columns("id, name").Values("?, ?").Args(1, "a").Exec()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idb.NewQuery().Select("*").From("intercept").Where("id = ?").Args(1).Rows(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idb.NewQuery().Update("no_such_table").Set("name = ?").Args("b").Exec(); err == nil {
+		t.Fatal("expect error")
+	}
+
+	var ops []string
+	for _, e := range inner.events {
+		ops = append(ops, string(e.Op)+" "+e.Statement)
+	}
+	if expect := "exec create, begin , exec insert, commit , query select, exec update"; strings.Join(ops, ", ") != expect {
+		t.Fatalf("expect %q, got %q", expect, strings.Join(ops, ", "))
+	}
+	insert := inner.events[2]
+	if insert.RowsAffected != 1 || len(insert.Args) != 2 || insert.Attempt != 1 || insert.Err != nil {
+		t.Fatalf("unexpected insert event %+v", insert)
+	}
+	if query := inner.events[4]; query.RowsAffected != -1 || query.Duration <= 0 {
+		t.Fatalf("unexpected query event %+v", query)
+	}
+	if update := inner.events[5]; update.Err == nil || update.SQL == "" {
+		t.Fatalf("unexpected update event %+v", update)
+	}
+}
@@ -0,0 +1,163 @@
+package xdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// rowScanner scan rows into buffers reused from row to row and map them to
+// structs, shared by ReflectRow, ReflectRows and Iterator
+type rowScanner struct {
+	rows    *interceptedRows
+	columns []string
+	vals    []rawValue
+	args    []interface{}
+	typ     reflect.Type
+	indexs  [][]int
+}
+
+func newRowScanner(rows *interceptedRows) (*rowScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	s := &rowScanner{
+		rows:    rows,
+		columns: columns,
+		vals:    make([]rawValue, len(columns)),
+		args:    make([]interface{}, len(columns)),
+	}
+	for i := range s.vals {
+		s.args[i] = &s.vals[i]
+	}
+	return s, nil
+}
+
+// scan the current row into the buffers
+func (s *rowScanner) scan() error {
+	return s.rows.Scan(s.args...)
+}
+
+// row copy the buffers into a new Row
+func (s *rowScanner) row() Row {
+	row := make(Row, len(s.columns))
+	for i, col := range s.columns {
+		row[col] = copyValue(s.vals[i])
+	}
+	return row
+}
+
+// setStruct set the fields of the addressable struct val from the buffers
+func (s *rowScanner) setStruct(val reflect.Value) error {
+	if typ := val.Type(); typ != s.typ {
+		s.typ, s.indexs = typ, getAllColumnFieldIndex(s.columns, typ)
+	}
+	for i, index := range s.indexs {
+		if len(index) == 0 {
+			continue
+		}
+		field := ensureValueFieldIndex(val, index)
+		if !field.IsValid() {
+			continue
+		}
+		if err := setFieldValue(field, sql.RawBytes(s.vals[i])); err != nil {
+			return fmt.Errorf("xdb: scan column %s: %v", s.columns[i], err)
+		}
+	}
+	return nil
+}
+
+func copyValue(b rawValue) Value {
+	if b == nil {
+		return nil
+	}
+	v := make(Value, len(b))
+	copy(v, b)
+	return v
+}
+
+// structValue return the struct dest points to
+func structValue(dest interface{}) (reflect.Value, error) {
+	val := reflect.ValueOf(dest)
+	ind := reflect.Indirect(val)
+	if val.Kind() != reflect.Ptr || ind.Kind() != reflect.Struct || ind.Type() == timeType {
+		return reflect.Value{}, errors.New("xdb rows must be ptr struct")
+	}
+	return ind, nil
+}
+
+type iterator struct {
+	*rowScanner
+}
+
+func (it *iterator) Next() bool {
+	return it.rows.Next()
+}
+
+func (it *iterator) Columns() []string {
+	return it.columns
+}
+
+func (it *iterator) Scan(dest ...interface{}) error {
+	return it.rows.Scan(dest...)
+}
+
+func (it *iterator) Row() (Row, error) {
+	if err := it.scan(); err != nil {
+		return nil, err
+	}
+	return it.row(), nil
+}
+
+func (it *iterator) ScanStruct(dest interface{}) error {
+	ind, err := structValue(dest)
+	if err != nil {
+		return err
+	}
+	if err := it.scan(); err != nil {
+		return err
+	}
+	return it.setStruct(ind)
+}
+
+func (it *iterator) Err() error {
+	return it.rows.Err()
+}
+
+func (it *iterator) Close() error {
+	return it.rows.Close()
+}
+
+func (q *query) Iterate() (Iterator, error) {
+	sqlRows, err := q.rows()
+	if err != nil {
+		return nil, err
+	}
+	s, err := newRowScanner(sqlRows)
+	if err != nil {
+		sqlRows.Close()
+		return nil, err
+	}
+	return &iterator{s}, nil
+}
+
+func (q *query) Each(fn func(Row) error) error {
+	it, err := q.Iterate()
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		row, err := it.Row()
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
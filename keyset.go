@@ -0,0 +1,315 @@
+package xdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeysetPage page of a keyset paginated select query
+type KeysetPage struct {
+	// Items the rows slice passed to Seek
+	Items interface{}
+	// Next cursor of the page after, empty on the last page
+	Next string
+	// Prev cursor of the page before, empty on the first page
+	Prev string
+}
+
+type seekKey struct {
+	expr   string
+	column string
+	desc   bool
+}
+
+// keysetCursor the keys of the row to seek after, their text and the kind of
+// Go value scanned so they are bound back with the same type
+type keysetCursor struct {
+	Prev   bool      `json:"p,omitempty"`
+	Values []*string `json:"v"`
+	Kinds  []string  `json:"k,omitempty"`
+
+	args []interface{}
+}
+
+// seekValue scan a key column into the buffer of the row scanner, keeping the
+// kind of the driver value
+type seekValue struct {
+	raw  *rawValue
+	kind string
+}
+
+func (v *seekValue) Scan(src interface{}) error {
+	switch src.(type) {
+	case int64:
+		v.kind = "i"
+	case float64:
+		v.kind = "f"
+	case bool:
+		v.kind = "b"
+	case time.Time:
+		v.kind = "t"
+	default:
+		v.kind = ""
+	}
+	return v.raw.Scan(src)
+}
+
+// keyArg the value of the key text of kind, as scanned by seekValue
+func keyArg(text, kind string) (interface{}, error) {
+	switch kind {
+	case "":
+		return text, nil
+	case "i":
+		return strconv.ParseInt(text, 10, 64)
+	case "f":
+		return strconv.ParseFloat(text, 64)
+	case "b":
+		return strconv.ParseBool(text)
+	case "t":
+		return time.Parse(time.RFC3339Nano, text)
+	}
+	return nil, fmt.Errorf("unknown kind %q", kind)
+}
+
+// Seek read the page after (or before) cursor into rows, a pointer to a slice
+// of structs or to Rows. keys is the ORDER BY of the page, eg. "created desc,
+// id desc", it must be unique and the key columns selected by their names,
+// NULL keys are not supported. Key values are bound back with the type the
+// driver scanned them as. An empty cursor reads the first page
+func (q *query) Seek(keys string, cursor string, size int64, rows interface{}) (*KeysetPage, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("xdb: page size %d, expect > 0", size)
+	}
+	if q._sql != "" || q._template != nil || q._statementType != selectStatement {
+		return nil, errors.New("xdb: Seek needs a Select query")
+	}
+	val := reflect.ValueOf(rows)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("xdb rows must be ptr slice")
+	}
+	ind := val.Elem()
+	elType := ind.Type().Elem()
+	isRow := elType == reflect.TypeOf(Row{})
+	if !isRow && !isStructType(elType) {
+		return nil, errors.New("xdb rows must be ptr struct")
+	}
+
+	seekKeys, err := parseSeekKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := decodeCursor(cursor, len(seekKeys))
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := iterate(q.seekQuery(seekKeys, cur, size))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	keyIndex := make([]int, len(seekKeys))
+	for i, key := range seekKeys {
+		keyIndex[i] = -1
+		for j, col := range it.columns {
+			if col == key.column {
+				keyIndex[i] = j
+			}
+		}
+		if keyIndex[i] == -1 {
+			return nil, fmt.Errorf("xdb: key column %s not selected", key.column)
+		}
+	}
+	seekValues := make([]*seekValue, len(keyIndex))
+	for i, index := range keyIndex {
+		seekValues[i] = &seekValue{raw: &it.vals[index]}
+		it.args[index] = seekValues[i]
+	}
+
+	items := reflect.MakeSlice(ind.Type(), 0, int(size))
+	var keyValues []keysetCursor
+	for int64(len(keyValues)) <= size && it.Next() {
+		if err := it.scan(); err != nil {
+			return nil, err
+		}
+		key := keysetCursor{Values: make([]*string, len(keyIndex)), Kinds: make([]string, len(keyIndex))}
+		for i, index := range keyIndex {
+			if it.vals[index] != nil {
+				v := string(it.vals[index])
+				key.Values[i], key.Kinds[i] = &v, seekValues[i].kind
+			}
+		}
+		keyValues = append(keyValues, key)
+
+		if isRow {
+			items = reflect.Append(items, reflect.ValueOf(it.row()))
+			continue
+		}
+		elem := reflect.New(elType).Elem()
+		target := elem
+		if elType.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elType.Elem()))
+			target = elem.Elem()
+		}
+		if err := it.setStruct(target); err != nil {
+			return nil, err
+		}
+		items = reflect.Append(items, elem)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	more := int64(len(keyValues)) > size
+	if more {
+		keyValues = keyValues[:size]
+		items = items.Slice(0, int(size))
+	}
+	if cur != nil && cur.Prev {
+		for i, j := 0, len(keyValues)-1; i < j; i, j = i+1, j-1 {
+			keyValues[i], keyValues[j] = keyValues[j], keyValues[i]
+			a, b := items.Index(i).Interface(), items.Index(j).Interface()
+			items.Index(i).Set(reflect.ValueOf(b))
+			items.Index(j).Set(reflect.ValueOf(a))
+		}
+	}
+	ind.Set(items)
+
+	page := &KeysetPage{Items: items.Interface()}
+	if len(keyValues) == 0 {
+		return page, nil
+	}
+	backward := cur != nil && cur.Prev
+	if (!backward && more) || (backward && cursor != "") {
+		next := keyValues[len(keyValues)-1]
+		page.Next = encodeCursor(&next)
+	}
+	if (!backward && cursor != "") || (backward && more) {
+		prev := keyValues[0]
+		prev.Prev = true
+		page.Prev = encodeCursor(&prev)
+	}
+	return page, nil
+}
+
+// seekQuery derive the page query from q, ordered by the keys, reversed to
+// read backward, after the cursor keys and limited to size+1 rows to see
+// whether there are more
+func (q *query) seekQuery(keys []seekKey, cur *keysetCursor, size int64) *query {
+	q.build()
+	c := q.clone()
+	backward := cur != nil && cur.Prev
+
+	c._orderBy = make([]string, len(keys))
+	for i, key := range keys {
+		desc := key.desc != backward
+		c._orderBy[i] = key.expr
+		if desc {
+			c._orderBy[i] += " DESC"
+		}
+	}
+	c._limit, c._page = nil, q.dialect().Limit(size+1, 0)
+
+	fixed := map[string]interface{}{}
+	if cur != nil {
+		var terms []string
+		for i, key := range keys {
+			var conds []string
+			for j := 0; j < i; j++ {
+				conds = append(conds, fmt.Sprintf("%s = ${:seek%d}", keys[j].expr, j))
+			}
+			op := ">"
+			if key.desc != backward {
+				op = "<"
+			}
+			conds = append(conds, fmt.Sprintf("%s %s ${:seek%d}", key.expr, op, i))
+			terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+			fixed[fmt.Sprintf(":seek%d", i)] = cur.args[i]
+		}
+		seek := "(" + strings.Join(terms, " OR ") + ")"
+		if len(c._where) > 0 {
+			buffer := new(strings.Builder)
+			for i, v := range c._where {
+				if i > 0 && v != and && v != or && c._where[i-1] != and && c._where[i-1] != or {
+					buffer.WriteString(" and ")
+				}
+				buffer.WriteString(v)
+			}
+			c._where = []string{"((" + buffer.String() + "))", seek}
+		} else {
+			c._where = []string{seek}
+		}
+	}
+	return q.bindFrom(c, fixed)
+}
+
+func parseSeekKeys(keys string) ([]seekKey, error) {
+	var seekKeys []seekKey
+	for _, item := range splitSQLList(keys) {
+		fields := strings.Fields(item)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("xdb: invalid seek key %q", item)
+		}
+		key := seekKey{expr: fields[0], column: fields[0]}
+		if i := strings.LastIndexByte(key.column, '.'); i != -1 {
+			key.column = key.column[i+1:]
+		}
+		if len(fields) == 2 {
+			switch strings.ToLower(fields[1]) {
+			case "asc":
+			case "desc":
+				key.desc = true
+			default:
+				return nil, fmt.Errorf("xdb: invalid seek key %q", item)
+			}
+		}
+		seekKeys = append(seekKeys, key)
+	}
+	return seekKeys, nil
+}
+
+func encodeCursor(cur *keysetCursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(cursor string, keys int) (*keysetCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("xdb: invalid cursor: %v", err)
+	}
+	cur := &keysetCursor{}
+	if err := json.Unmarshal(b, cur); err != nil {
+		return nil, fmt.Errorf("xdb: invalid cursor: %v", err)
+	}
+	if len(cur.Values) != keys {
+		return nil, fmt.Errorf("xdb: cursor of %d keys, expect %d", len(cur.Values), keys)
+	}
+	if len(cur.Kinds) != 0 && len(cur.Kinds) != keys {
+		return nil, fmt.Errorf("xdb: cursor of %d key kinds, expect %d", len(cur.Kinds), keys)
+	}
+	cur.args = make([]interface{}, keys)
+	for i, v := range cur.Values {
+		if v == nil {
+			return nil, errors.New("xdb: cursor with NULL key")
+		}
+		kind := ""
+		if len(cur.Kinds) != 0 {
+			kind = cur.Kinds[i]
+		}
+		if cur.args[i], err = keyArg(*v, kind); err != nil {
+			return nil, fmt.Errorf("xdb: invalid cursor: %v", err)
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,144 @@
+package xdb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSeek(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `seek` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `name` VARCHAR(64) NULL, `grp` INTEGER NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `seek`").Exec()
+	for i := 0; i < 23; i++ {
+		if _, err := ndb.NewQuery().InsertInto("seek").Columns("name, grp").Values("?, ?").Args(fmt.Sprintf("name-%d", i), i%4).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type Entity struct {
+		ID  int64 `db:"id"`
+		Grp int64 `db:"grp"`
+	}
+
+	var (
+		es     []Entity
+		ids    []int64
+		cursor string
+		pages  int
+	)
+	for {
+		p, err := ndb.NewQuery().Select("id, grp").From("seek").Where("id > ?").Or().Where("grp = ?").Args(3, 0).Seek("grp desc, id", cursor, 5, &es)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, e := range es {
+			ids = append(ids, e.ID)
+		}
+		if (pages == 1) != (p.Prev == "") {
+			t.Fatalf("unexpected prev cursor of page %d", pages)
+		}
+		if p.Next == "" {
+			break
+		}
+		cursor = p.Next
+	}
+	if pages != 5 || len(ids) != 21 || ids[0] != 4 || ids[1] != 8 || ids[20] != 21 {
+		t.Fatal("unexpected ids", pages, ids)
+	}
+
+	var rows Rows
+	first, err := ndb.NewQuery().Select("s.id, s.name").From("seek s").Seek("s.id desc", "", 10, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ndb.NewQuery().Select("s.id, s.name").From("seek s").Seek("s.id desc", first.Next, 10, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 10 || rows[0].Get("id").Int() != 13 || second.Prev == "" || second.Next == "" {
+		t.Fatal("unexpected rows", rows)
+	}
+	back, err := ndb.NewQuery().Select("s.id, s.name").From("seek s").Seek("s.id desc", second.Prev, 10, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 10 || rows[0].Get("id").Int() != 23 || rows[9].Get("id").Int() != 14 || back.Prev != "" || back.Next == "" {
+		t.Fatalf("unexpected back page %+v %v", back, rows)
+	}
+
+	if _, err := ndb.NewQuery().Select("id").From("seek").Seek("id", "bad cursor", 10, &rows); err == nil {
+		t.Fatal("expect invalid cursor error")
+	}
+	if _, err := ndb.NewQuery().Select("name").From("seek").Seek("id", "", 10, &rows); err == nil {
+		t.Fatal("expect key column error")
+	}
+}
+
+func TestSeekQueryDialect(t *testing.T) {
+	keys, err := parseSeekKeys("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		dialect Dialect
+		expect  string
+	}{
+		{Postgres, "SELECT id\nFROM t\nORDER BY id\nLIMIT 11"},
+		{SQLite, "SELECT id\nFROM t\nORDER BY id\nLIMIT 11"},
+		{SQLServer, "SELECT id\nFROM t\nORDER BY id\nOFFSET 0 ROWS FETCH NEXT 11 ROWS ONLY"},
+		{Oracle, "SELECT id\nFROM t\nORDER BY id\nOFFSET 0 ROWS FETCH NEXT 11 ROWS ONLY"},
+	}
+	for _, c := range cases {
+		q := newQuery(nil, newConfig([]Option{WithDialect(c.dialect)}))
+		q.Select("id").From("t").Limit("100")
+		rawSQL, _, err := q.seekQuery(keys, nil, 10).bind()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rawSQL != c.expect {
+			t.Fatalf("%s: expect %q, got %q", c.dialect.Name(), c.expect, rawSQL)
+		}
+	}
+}
+
+func TestSeekTimeKey(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `seek_time` (`id` INTEGER PRIMARY KEY, `created` DATETIME NOT NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `seek_time`").Exec()
+	base := time.Date(2017, 10, 1, 8, 0, 0, 0, time.UTC)
+	for i := 1; i <= 10; i++ {
+		created := base.Add(time.Duration(i/3) * time.Minute)
+		if _, err := ndb.NewQuery().InsertInto("seek_time").Columns("id, created").Values("?, ?").Args(i, created).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type Entity struct {
+		ID      int64     `db:"id"`
+		Created time.Time `db:"created"`
+	}
+	var (
+		es     []Entity
+		ids    []int64
+		cursor string
+	)
+	for pages := 0; pages < 5; pages++ {
+		p, err := ndb.NewQuery().Select("id, created").From("seek_time").Seek("created desc, id", cursor, 3, &es)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range es {
+			ids = append(ids, e.ID)
+		}
+		if cursor = p.Next; cursor == "" {
+			break
+		}
+	}
+	if fmt.Sprint(ids) != "[9 10 6 7 8 3 4 5 1 2]" {
+		t.Fatal("unexpected ids", ids)
+	}
+}
@@ -0,0 +1,152 @@
+package xdb
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structInfo mapping metadata of a struct type, shared by every query
+type structInfo struct {
+	// columns column name to field index, matched by tag or field name
+	columns map[string][]int
+	// properties ${token} to field index, matched by tag, field name when untagged
+	properties map[string][]int
+	// fields exported fields with their column and tag options, embedded
+	// structs flattened
+	fields []*fieldInfo
+}
+
+// fieldInfo field of a struct mapped to a column
+//
+//	`db:"id,pk,autoincr"` primary key filled from LastInsertId
+//	`db:"name,omitempty"` skipped by insert and update when zero
+//	`db:"created,readonly"` never written
+//	`db:"-"` ignored
+type fieldInfo struct {
+	column    string
+	index     []int
+	pk        bool
+	autoIncr  bool
+	omitEmpty bool
+	readonly  bool
+}
+
+// parseTag split the db tag into the column name and options
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := make(map[string]bool, len(parts)-1)
+	for _, opt := range parts[1:] {
+		opts[strings.TrimSpace(opt)] = true
+	}
+	return strings.TrimSpace(parts[0]), opts
+}
+
+var structInfos sync.Map // map[reflect.Type]*structInfo
+
+// getStructInfo return the cached mapping metadata of typ, a pointer type is
+// resolved to its element
+func getStructInfo(typ reflect.Type) *structInfo {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if info, ok := structInfos.Load(typ); ok {
+		return info.(*structInfo)
+	}
+	info, _ := structInfos.LoadOrStore(typ, newStructInfo(typ))
+	return info.(*structInfo)
+}
+
+func newStructInfo(typ reflect.Type) *structInfo {
+	info := &structInfo{
+		columns:    map[string][]int{},
+		properties: map[string][]int{},
+	}
+	if typ.Kind() == reflect.Struct && !typ.ConvertibleTo(timeType) {
+		info.walk(typ, nil, map[reflect.Type]bool{typ: true})
+	}
+	return info
+}
+
+// walk add the fields of typ in declaration order, fields of embedded structs
+// before the embedded field itself, the first match of a name wins
+func (info *structInfo) walk(typ reflect.Type, parent []int, visiting map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, opts := parseTag(f.Tag.Get(tagName))
+		if name == "-" {
+			continue
+		}
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+
+		flattened := false
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !ft.ConvertibleTo(timeType) && !visiting[ft] {
+				visiting[ft] = true
+				info.walk(ft, index, visiting)
+				delete(visiting, ft)
+				flattened = true
+			}
+		}
+
+		if name != "" {
+			addFieldIndex(info.columns, name, index)
+		}
+		addFieldIndex(info.columns, f.Name, index)
+
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		addFieldIndex(info.properties, name, index)
+		if flattened || info.hasField(name) {
+			continue
+		}
+		info.fields = append(info.fields, &fieldInfo{
+			column:    name,
+			index:     index,
+			pk:        opts["pk"],
+			autoIncr:  opts["autoincr"],
+			omitEmpty: opts["omitempty"],
+			readonly:  opts["readonly"],
+		})
+	}
+}
+
+func (info *structInfo) hasField(column string) bool {
+	for _, f := range info.fields {
+		if f.column == column {
+			return true
+		}
+	}
+	return false
+}
+
+func addFieldIndex(m map[string][]int, name string, index []int) {
+	if _, ok := m[name]; !ok {
+		m[name] = index
+	}
+}
+
+// fieldByIndex return the field of val at index, invalid when a nil embedded
+// pointer is on the way
+func fieldByIndex(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val
+}
@@ -0,0 +1,123 @@
+package xdb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type benchBase struct {
+	ID      int64     `db:"id"`
+	Created time.Time `db:"created"`
+}
+
+type benchEntity struct {
+	benchBase
+	Username string `db:"username"`
+	Depart   string `db:"departname"`
+	Email    string
+	Phone    string
+	Address  string
+	City     string
+	Country  string
+	Score    float64 `db:"score"`
+}
+
+var benchColumns = []string{"id", "created", "username", "departname", "Email", "Phone", "Address", "City", "Country", "score"}
+
+func TestStructInfo(t *testing.T) {
+	typ := reflect.TypeOf(&benchEntity{})
+	for _, column := range benchColumns {
+		if index := getColumnFieldIndex(column, typ); index == nil {
+			t.Fatal("column not mapped", column)
+		}
+	}
+	if index := getColumnFieldIndex("id", typ); !reflect.DeepEqual(index, []int{0, 0}) {
+		t.Fatal("expect [0 0], got", index)
+	}
+	if getStructInfo(typ) != getStructInfo(typ.Elem()) {
+		t.Fatal("expect cached struct info")
+	}
+	if val, ok := reflectGetValue(&benchEntity{Depart: "dev"}, "departname"); !ok || val != "dev" {
+		t.Fatal("expect dev, got", val, ok)
+	}
+
+	type node struct {
+		*node
+		Name string
+	}
+	if index := getColumnFieldIndex("Name", reflect.TypeOf(node{})); !reflect.DeepEqual(index, []int{1}) {
+		t.Fatal("expect [1], got", index)
+	}
+}
+
+func BenchmarkColumnFieldIndexUncached(b *testing.B) {
+	typ := reflect.TypeOf(benchEntity{})
+	for i := 0; i < b.N; i++ {
+		info := newStructInfo(typ)
+		for _, column := range benchColumns {
+			_ = info.columns[column]
+		}
+	}
+}
+
+func BenchmarkColumnFieldIndex(b *testing.B) {
+	typ := reflect.TypeOf(benchEntity{})
+	for i := 0; i < b.N; i++ {
+		getAllColumnFieldIndex(benchColumns, typ)
+	}
+}
+
+func BenchmarkReflectGetValueUncached(b *testing.B) {
+	e := &benchEntity{Score: 1}
+	for i := 0; i < b.N; i++ {
+		structInfos.Delete(reflect.TypeOf(benchEntity{}))
+		reflectGetValue(e, "score")
+	}
+}
+
+func BenchmarkReflectGetValue(b *testing.B) {
+	e := &benchEntity{Score: 1}
+	for i := 0; i < b.N; i++ {
+		reflectGetValue(e, "score")
+	}
+}
+
+func BenchmarkReflectRows(b *testing.B) {
+	log := LogFunc
+	LogFunc = nil
+	defer func() { LogFunc = log }()
+
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE IF NOT EXISTS `bench` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `created` STRING, `username` STRING, `departname` STRING, `Email` STRING, `Phone` STRING, `Address` STRING, `City` STRING, `Country` STRING, `score` REAL)").Exec(); err != nil {
+		b.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `bench`").Exec()
+
+	tx, err := ndb.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	q := tx.NewQuery().InsertInto("bench").Columns("created, username, departname, Email, Phone, Address, City, Country, score").Values("${created}, ${username}, ${departname}, ${Email}, ${Phone}, ${Address}, ${City}, ${Country}, ${score}")
+	if err = q.Prepare(); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		e := &benchEntity{benchBase: benchBase{Created: time.Now()}, Username: fmt.Sprintf("user-%d", i), Score: float64(i)}
+		if _, err = q.ReflectArgs(e).Exec(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	q.Close()
+	if err = tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var es []*benchEntity
+		if _, err := ndb.NewQuery().Select("*").From("bench").ReflectRows(&es); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
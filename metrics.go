@@ -0,0 +1,148 @@
+package xdb
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics receive the measure of every exec and query of a DB, eg. a bridge
+// to prometheus counters and histograms labelled by statement and fingerprint
+type Metrics interface {
+	// ObserveQuery statement is insert, delete, update, select or the first
+	// keyword of a raw sql, fingerprint the normalized sql, err nil on success
+	ObserveQuery(statement, fingerprint string, duration time.Duration, err error)
+}
+
+// WithMetrics observe the exec and query of the DB with metrics
+func WithMetrics(metrics Metrics) Option {
+	return func(cfg *config) {
+		if metrics != nil {
+			cfg.interceptors = append(cfg.interceptors, &metricsInterceptor{metrics: metrics})
+		}
+	}
+}
+
+type metricsInterceptor struct {
+	metrics Metrics
+}
+
+func (m *metricsInterceptor) Before(ctx context.Context, e *Event) context.Context {
+	return ctx
+}
+
+func (m *metricsInterceptor) After(ctx context.Context, e *Event) {
+	if e.Op == OpExec || e.Op == OpQuery {
+		m.metrics.ObserveQuery(e.Statement, normalizeSQL(e.SQL), e.Duration, e.Err)
+	}
+}
+
+// DefaultBuckets latency histogram buckets of MemoryMetrics
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// QueryStats measure of a statement and fingerprint
+type QueryStats struct {
+	Statement   string
+	Fingerprint string
+	Count       int64
+	Errors      int64
+	Total       time.Duration
+	Max         time.Duration
+	// Buckets upper bounds of the histogram
+	Buckets []time.Duration
+	// Counts queries per bucket, the last one over all the buckets
+	Counts []int64
+}
+
+// Mean average duration
+func (s QueryStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+type metricsKey struct {
+	statement   string
+	fingerprint string
+}
+
+// MemoryMetrics Metrics kept in memory, for tests and debug pages
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	stats   map[metricsKey]*QueryStats
+}
+
+// NewMemoryMetrics in memory metrics with the histogram buckets, sorted,
+// default DefaultBuckets
+func NewMemoryMetrics(buckets ...time.Duration) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &MemoryMetrics{buckets: buckets, stats: map[metricsKey]*QueryStats{}}
+}
+
+// ObserveQuery add the measure
+func (m *MemoryMetrics) ObserveQuery(statement, fingerprint string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricsKey{statement: statement, fingerprint: fingerprint}
+	stats := m.stats[key]
+	if stats == nil {
+		stats = &QueryStats{
+			Statement:   statement,
+			Fingerprint: fingerprint,
+			Buckets:     m.buckets,
+			Counts:      make([]int64, len(m.buckets)+1),
+		}
+		m.stats[key] = stats
+	}
+	stats.Count++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.Total += duration
+	if duration > stats.Max {
+		stats.Max = duration
+	}
+	stats.Counts[sort.Search(len(m.buckets), func(i int) bool { return duration <= m.buckets[i] })]++
+}
+
+// Snapshot copy of the stats sorted by statement and fingerprint
+func (m *MemoryMetrics) Snapshot() []QueryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make([]QueryStats, 0, len(m.stats))
+	for _, stats := range m.stats {
+		s := *stats
+		s.Counts = append([]int64(nil), stats.Counts...)
+		snapshot = append(snapshot, s)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Statement != snapshot[j].Statement {
+			return snapshot[i].Statement < snapshot[j].Statement
+		}
+		return snapshot[i].Fingerprint < snapshot[j].Fingerprint
+	})
+	return snapshot
+}
+
+// Reset drop the stats
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = map[metricsKey]*QueryStats{}
+}
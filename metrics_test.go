@@ -0,0 +1,45 @@
+package xdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	metrics := NewMemoryMetrics(time.Hour)
+	mdb := New(db, WithMetrics(metrics))
+	if _, err := mdb.NewQuery().SQL("CREATE TABLE `metrics` (`id` INTEGER PRIMARY KEY)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer mdb.NewQuery().SQL("DROP TABLE `metrics`").Exec()
+	metrics.Reset()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := mdb.NewQuery().InsertInto("metrics").Columns("id").Values("?").Args(i).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mdb.NewQuery().InsertInto("metrics").Columns("id").Values("?").Args(1).Exec(); err == nil {
+		t.Fatal("expect unique error")
+	}
+	if _, err := mdb.NewQuery().Select("id").From("metrics").Where("id in (${Ids})").ReflectArgs(map[string]interface{}{"Ids": []int{1, 2}}).Rows(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatal("expect 2 fingerprints, got", snapshot)
+	}
+	insert, sel := snapshot[0], snapshot[1]
+	if insert.Statement != "insert" || insert.Count != 4 || insert.Errors != 1 || insert.Counts[0] != 4 || insert.Mean() <= 0 {
+		t.Fatalf("unexpected insert stats %+v", insert)
+	}
+	if sel.Statement != "select" || sel.Fingerprint != "SELECT id FROM metrics WHERE (id in (?))" || sel.Count != 1 || len(sel.Counts) != 2 {
+		t.Fatalf("unexpected select stats %+v", sel)
+	}
+
+	metrics.Reset()
+	if len(metrics.Snapshot()) != 0 {
+		t.Fatal("expect empty snapshot")
+	}
+}
@@ -0,0 +1,182 @@
+package xdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Page page of a select query
+type Page struct {
+	// Items the rows slice passed to Paginate
+	Items interface{}
+	Total int64
+	// Page 1-based page number
+	Page  int64
+	Size  int64
+	Pages int64
+}
+
+// HasNext return there is a page after this one
+func (p *Page) HasNext() bool {
+	return p.Page < p.Pages
+}
+
+// HasPrev return there is a page before this one
+func (p *Page) HasPrev() bool {
+	return p.Page > 1
+}
+
+// Paginate run the count query derived from q and the page-th page of q into
+// rows, a pointer to a slice of structs or to Rows. The count drops ORDER BY
+// and LIMIT, queries with DISTINCT or GROUP BY, and SQL or Template queries,
+// are counted as a subquery. The page replaces the Limit of a builder query
+// by the Limit clause of the dialect, SQL with a limit of its own is paged
+// as a subquery
+func (q *query) Paginate(page, size int64, rows interface{}) (*Page, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("xdb: page size %d, expect > 0", size)
+	}
+	if page < 1 {
+		page = 1
+	}
+	val := reflect.ValueOf(rows)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("xdb rows must be ptr slice")
+	}
+	ind := val.Elem()
+	ind.Set(ind.Slice(0, 0))
+
+	total, err := q.countQuery().Value()
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{
+		Total: total.Int(),
+		Page:  page,
+		Size:  size,
+	}
+	p.Pages = (p.Total + size - 1) / size
+
+	if offset := (page - 1) * size; offset < p.Total {
+		data := q.pageQuery(size, offset)
+		if rs, ok := rows.(*Rows); ok {
+			*rs, err = data.Rows()
+		} else if rs, ok := rows.(*[]Row); ok {
+			*rs, err = data.Rows()
+		} else {
+			_, err = data.ReflectRows(rows)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.Items = ind.Interface()
+	return p, nil
+}
+
+// clone copy the clauses, args and context of q into a new unbuilt query
+func (q *query) clone() *query {
+	c := *q
+	c.sqlType, c.parts, c.params, c.tokens, c.rawSQL = 0, nil, nil, nil, ""
+	c.stmt, c.stmtSQL = nil, ""
+	return &c
+}
+
+// bindFrom bind the args of q to the derived query d, by name for
+// ReflectArgs, by position for Args skipping the placeholders of dropped
+// clauses. tokens in fixed are bound to their value, eg. keyset cursors
+func (q *query) bindFrom(d *query, fixed map[string]interface{}) *query {
+	d._template = nil
+	if q.reflectArgs != nil {
+		d.ReflectArgs(q.reflectArgs)
+	} else {
+		d.build()
+		args := make([]interface{}, 0, len(d.params))
+		next := 0
+		for _, param := range d.params {
+			if _, ok := fixed[param]; ok || next >= len(q.args) {
+				args = append(args, nil)
+				continue
+			}
+			args = append(args, q.args[next])
+			next++
+		}
+		d.Args(args...)
+	}
+	for i, param := range d.params {
+		if val, ok := fixed[param]; ok && i < len(d.args) {
+			d.args[i] = val
+		}
+	}
+	return d
+}
+
+func (q *query) countQuery() *query {
+	q.build()
+	c := q.clone()
+	if q._sql != "" || q._statementType != selectStatement {
+		c._sql = "SELECT count(*) FROM (\n" + q.String() + "\n) t"
+		c.args = q.args
+		c.reflectArgs = nil
+		c._template = nil
+		return c
+	}
+
+	c._orderBy, c._limit = nil, nil
+	// placeholders in the select list must be kept to bind args by position
+	columns := strings.Join(c._select, ", ")
+	if c._distinct || len(c._groupBy) > 0 || strings.Contains(columns, "?") || strings.Contains(columns, "${") {
+		c._sql = "SELECT count(*) FROM (\n" + c.String() + "\n) t"
+	} else {
+		c._select = []string{"count(*)"}
+	}
+	return q.bindFrom(c, nil)
+}
+
+// pageQuery derive the query of size rows after offset from q with the page
+// clause of the dialect, replacing the LIMIT of q. SQL or Template queries
+// already limited are paged as a subquery
+func (q *query) pageQuery(size, offset int64) *query {
+	c := q.clone()
+	page, order := q.dialect().Limit(size, offset), pageOrder(q.dialect())
+	if q._sql != "" || q._statementType != selectStatement {
+		c._sql = pagedSQL(q.String(), page, order)
+		c.args = q.args
+		c.reflectArgs = nil
+		c._template = nil
+		return c
+	}
+	if len(c._orderBy) == 0 && order != "" {
+		c._orderBy = []string{order}
+	}
+	c._limit, c._page = nil, page
+	return q.bindFrom(c, nil)
+}
+
+// pageOrder ORDER BY of a page of an unordered select, sqlserver can't
+// OFFSET without one
+func pageOrder(d Dialect) string {
+	if _, ok := d.(sqlserverDialect); ok {
+		return "(SELECT NULL)"
+	}
+	return ""
+}
+
+// limitPattern a trailing LIMIT or FETCH clause, or a leading TOP
+var limitPattern = regexp.MustCompile(`(?is)(\b(limit|fetch\s+(first|next))\s[^()]*$)|^\s*select\s+(distinct\s+)?top\b`)
+
+// pagedSQL append the page clause to rawSQL, or select from rawSQL when it
+// has a limit of its own, ordered by order if any
+func pagedSQL(rawSQL, page, order string) string {
+	rawSQL = strings.TrimRight(strings.TrimSpace(rawSQL), ";")
+	if limitPattern.MatchString(rawSQL) {
+		if order != "" {
+			page = "ORDER BY " + order + "\n" + page
+		}
+		return "SELECT * FROM (\n" + rawSQL + "\n) t\n" + page
+	}
+	return rawSQL + "\n" + page
+}
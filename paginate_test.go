@@ -0,0 +1,80 @@
+package xdb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `page` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `name` VARCHAR(64) NULL, `grp` INTEGER NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `page`").Exec()
+	for i := 0; i < 23; i++ {
+		if _, err := ndb.NewQuery().InsertInto("page").Columns("name, grp").Values("?, ?").Args(fmt.Sprintf("name-%d", i), i%4).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type Entity struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	var es []Entity
+	p, err := ndb.NewQuery().Select("id, name").From("page").Where("id > ${Min}").OrderBy("id").Limit("${Max}").ReflectArgs(map[string]interface{}{"Min": 2, "Max": 100}).Paginate(2, 5, &es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 21 || p.Pages != 5 || !p.HasNext() || !p.HasPrev() || len(es) != 5 || es[0].ID != 8 {
+		t.Fatalf("unexpected page %+v %v", p, es)
+	}
+	if items, ok := p.Items.([]Entity); !ok || len(items) != 5 {
+		t.Fatal("unexpected items", p.Items)
+	}
+
+	p, err = ndb.NewQuery().Select("id, name").From("page").Where("id > ?").OrderBy("id desc").Limit("?").Args(2, 100).Paginate(5, 5, &es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 21 || p.HasNext() || len(es) != 1 || es[0].ID != 3 {
+		t.Fatalf("unexpected page %+v %v", p, es)
+	}
+
+	var rows Rows
+	p, err = ndb.NewQuery().Select("grp, count(*) as n").From("page").GroupBy("grp").OrderBy("grp").Paginate(1, 3, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 4 || p.Pages != 2 || len(rows) != 3 || rows[0].Get("n").Int() != 6 {
+		t.Fatalf("unexpected page %+v %v", p, rows)
+	}
+
+	p, err = ndb.NewQuery().SelectDistinct("grp").From("page").Paginate(9, 3, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 4 || len(rows) != 0 {
+		t.Fatalf("unexpected page %+v %v", p, rows)
+	}
+
+	p, err = ndb.NewQuery().SQL("select id, name from page where grp = ? order by id").Args(1).Paginate(2, 4, &es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 6 || len(es) != 2 || es[0].ID != 18 {
+		t.Fatalf("unexpected page %+v %v", p, es)
+	}
+
+	p, err = ndb.NewQuery().SQL("select id, name from page order by id limit ?").Args(10).Paginate(3, 4, &es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 10 || len(es) != 2 || es[0].ID != 9 {
+		t.Fatalf("unexpected page %+v %v", p, es)
+	}
+
+	if _, err = ndb.NewQuery().Select("id").From("page").Paginate(1, 0, &es); err == nil {
+		t.Fatal("expect error for size 0")
+	}
+}
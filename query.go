@@ -2,7 +2,9 @@ package xdb
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
@@ -21,6 +23,20 @@ const (
 	selectStatement
 )
 
+func (t statementType) String() string {
+	switch t {
+	case insertStatement:
+		return "insert"
+	case deleteStatement:
+		return "delete"
+	case updateStatement:
+		return "update"
+	case selectStatement:
+		return "select"
+	}
+	return ""
+}
+
 const (
 	and = ") \n and ("
 	or  = ") \n or ("
@@ -46,22 +62,44 @@ type query struct {
 	_orderBy        []string
 	_columns        []string
 	_values         []string
+	_valueRows      []string
+	_conflict       []string
+	_update         []string
+	_upsert         bool
+	_doNothing      bool
 	_sets           []string
 	_limit          []string
+	_page           string
 	_sql            string
+	_template       []tplNode
 	_args           []interface{}
 	_distinct       bool
 	_lastCondition  int
 	_statementType  statementType
 
 	sqlType statementType
+	parts   []string
+	params  []string
 	tokens  []string
 	rawSQL  string
 
-	args []interface{}
+	args        []interface{}
+	reflectArgs interface{}
+	argsErr     error
+	upsertErr   error
+	err         error
 
+	ctx     context.Context
 	querier Querier
 	stmt    *sql.Stmt
+	stmtSQL string
+	cfg     *config
+	// retry Exec with the retry policy of cfg, not in a transaction
+	retry bool
+}
+
+func newQuery(querier Querier, cfg *config) *query {
+	return &query{querier: querier, cfg: cfg}
 }
 
 func (q *query) Update(table string) Query {
@@ -92,11 +130,43 @@ func (q *query) Values(values string) Query {
 	return q
 }
 
+func (q *query) ValuesRow(values string) Query {
+	q._valueRows = append(q._valueRows, values)
+	return q
+}
+
+// valueRows the rows of VALUES, the Values joined as the first row followed
+// by every ValuesRow
+func (q *query) valueRows() []string {
+	if len(q._values) == 0 {
+		return q._valueRows
+	}
+	return append([]string{strings.Join(q._values, ", ")}, q._valueRows...)
+}
+
 func (q *query) Columns(columns string) Query {
 	q._columns = append(q._columns, columns)
 	return q
 }
 
+func (q *query) OnConflict(columns string) Query {
+	q._conflict = append(q._conflict, splitSQLList(columns)...)
+	q._upsert = true
+	return q
+}
+
+func (q *query) DoUpdate(columns string) Query {
+	q._update = append(q._update, splitSQLList(columns)...)
+	q._upsert = true
+	return q
+}
+
+func (q *query) DoNothing() Query {
+	q._doNothing = true
+	q._upsert = true
+	return q
+}
+
 func (q *query) Select(columns string) Query {
 	q._select = append(q._select, columns)
 	q._statementType = selectStatement
@@ -171,8 +241,8 @@ func (q *query) addCondition(condition string) {
 	}
 }
 
-func (q *query) GroupBy(having string) Query {
-	q._groupBy = append(q._having, having)
+func (q *query) GroupBy(groupBy string) Query {
+	q._groupBy = append(q._groupBy, groupBy)
 	return q
 }
 
@@ -191,6 +261,41 @@ func (q *query) SQL(sqlString string) Query {
 	return q
 }
 
+func (q *query) Template(tpl string) Query {
+	q._template, q.err = parseTemplate(tpl)
+	if q.err == nil && q._template == nil {
+		q._template = []tplNode{}
+	}
+	q._sql = ""
+	q.parts = nil
+	return q
+}
+
+// renderTemplate render the template with data into _sql, the query is
+// rebuilt as every data may render another sql
+func (q *query) renderTemplate(data interface{}) {
+	if q._template == nil || q.err != nil {
+		return
+	}
+	q._sql, q.argsErr = executeTemplate(q._template, data)
+	q.parts = nil
+}
+
+func (q *query) WithContext(ctx context.Context) Query {
+	if ctx == nil {
+		panic("xdb: nil context")
+	}
+	q.ctx = ctx
+	return q
+}
+
+func (q *query) Context() context.Context {
+	if q.ctx != nil {
+		return q.ctx
+	}
+	return context.Background()
+}
+
 func sqlClause(buffer *bytes.Buffer, keyword string, parts []string, openWord string, closeWord string, conjunction string) {
 	if len(parts) != 0 {
 		if buffer.Len() != 0 {
@@ -229,6 +334,9 @@ func (q *query) selectSQL(buffer *bytes.Buffer) {
 	sqlClause(buffer, "HAVING", q._having, "(", ")", " and ")
 	sqlClause(buffer, "ORDER BY", q._orderBy, "", "", ", ")
 	sqlClause(buffer, "LIMIT", q._limit, "", "", "")
+	if q._page != "" {
+		buffer.WriteString("\n" + q._page)
+	}
 }
 
 func (q *query) updateSQL(buffer *bytes.Buffer) {
@@ -243,9 +351,44 @@ func (q *query) deleteSQL(buffer *bytes.Buffer) {
 }
 
 func (q *query) insertSQL(buffer *bytes.Buffer) {
+	if q._upsert {
+		q.upsertSQL(buffer)
+		return
+	}
 	sqlClause(buffer, "INSERT INTO", q._tables, "", "", "")
 	sqlClause(buffer, "", q._columns, "(", ")", ", ")
-	sqlClause(buffer, "VALUES", q._values, "(", ")", ", ")
+	sqlClause(buffer, "VALUES", q.valueRows(), "(", ")", "), (")
+}
+
+// upsertSQL render the upsert with the dialect, without DoUpdate every
+// inserted column but the conflict ones is updated
+func (q *query) upsertSQL(buffer *bytes.Buffer) {
+	u := Upsert{
+		Table:    strings.Join(q._tables, ", "),
+		Values:   q.valueRows(),
+		Conflict: q._conflict,
+	}
+	for _, columns := range q._columns {
+		u.Columns = append(u.Columns, splitSQLList(columns)...)
+	}
+	switch {
+	case q._doNothing:
+	case len(q._update) > 0:
+		u.Update = q._update
+	default:
+		for _, col := range u.Columns {
+			conflict := false
+			for _, c := range u.Conflict {
+				conflict = conflict || c == col
+			}
+			if !conflict {
+				u.Update = append(u.Update, col)
+			}
+		}
+	}
+	var str string
+	str, q.upsertErr = q.dialect().Upsert(u)
+	buffer.WriteString(str)
 }
 
 func (q *query) String() string {
@@ -273,52 +416,186 @@ func (q *query) String() string {
 }
 
 func (q *query) build() {
-	if q.stmt == nil && q.rawSQL == "" {
+	if q.parts == nil {
+		if q._template != nil && q._sql == "" {
+			q.renderTemplate(nil)
+		}
 		str := q.String()
 		q.sqlType = q._statementType
-		q.rawSQL, q.tokens = q.parseToken(str, "${", "}")
+		q.parts, q.params = q.parseToken(str, "${", "}")
+		q.tokens = q.tokens[:0]
+		for _, param := range q.params {
+			if param != "" {
+				q.tokens = append(q.tokens, param)
+			}
+		}
+		q.rawSQL, _, _ = q.render(nil)
 	}
 }
 
-func (q *query) parseToken(str, openToken, closeToken string) (string, []string) {
+// parseToken split str around ${token} and bare "?" placeholders, "?" inside
+// quoted literals and escaped \${ are kept as they are. params holds the
+// token of every placeholder, "" for "?"
+func (q *query) parseToken(str, openToken, closeToken string) (parts []string, params []string) {
 	buffer := new(bytes.Buffer)
-	var tokens []string
-	start := strings.Index(str, openToken)
-	for start > -1 {
-		if start > 0 && str[start-1] == '\\' {
-			buffer.WriteString(str[:start])
+	var quote byte
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c == '\\' && strings.HasPrefix(str[i+1:], openToken) {
 			buffer.WriteString(openToken)
-			str = str[start+len(openToken):]
-		} else {
-			offset := strings.Index(str[start:], closeToken)
-			if offset == -1 {
-				buffer.WriteString(str[:])
-				str = ""
-			} else {
-				buffer.WriteString(str[:start])
-				token := str[start+len(openToken) : start+offset]
-				tokens = append(tokens, token)
-				buffer.WriteString(q.handleToken(token))
-				str = str[start+offset+len(closeToken):]
+			i += len(openToken)
+			continue
+		}
+		if strings.HasPrefix(str[i:], openToken) {
+			if offset := strings.Index(str[i+len(openToken):], closeToken); offset != -1 {
+				parts = append(parts, buffer.String())
+				params = append(params, str[i+len(openToken):i+len(openToken)+offset])
+				buffer.Reset()
+				i += len(openToken) + offset + len(closeToken) - 1
+				continue
+			}
+		}
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
 			}
-			start = strings.Index(str, openToken)
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			parts = append(parts, buffer.String())
+			params = append(params, "")
+			buffer.Reset()
+			continue
 		}
+		buffer.WriteByte(c)
 	}
-	buffer.WriteString(str)
-	return buffer.String(), tokens
+	parts = append(parts, buffer.String())
+	return parts, params
+}
+
+// render join parts with the bind parameters of the dialect. slice and array
+// args of ${} tokens are expanded into one placeholder per element, an empty
+// one is an error as no list is right for both IN and NOT IN. args of bare
+// "?" are bound as they are, eg. a postgres array
+func (q *query) render(args []interface{}) (string, []interface{}, error) {
+	buffer := new(bytes.Buffer)
+	var flat []interface{}
+	index := 0
+	for i, part := range q.parts {
+		buffer.WriteString(part)
+		if i >= len(q.params) {
+			break
+		}
+		if i >= len(args) {
+			index++
+			buffer.WriteString(q.handleToken(index))
+			continue
+		}
+		if vals, ok := expandArg(args[i]); ok && q.params[i] != "" {
+			if len(vals) == 0 {
+				return "", nil, fmt.Errorf("xdb: empty slice of ${%s}", q.params[i])
+			}
+			for j, val := range vals {
+				if j > 0 {
+					buffer.WriteString(", ")
+				}
+				index++
+				buffer.WriteString(q.handleToken(index))
+				flat = append(flat, val)
+			}
+			continue
+		}
+		index++
+		buffer.WriteString(q.handleToken(index))
+		flat = append(flat, args[i])
+	}
+	if len(args) > len(q.params) {
+		flat = append(flat, args[len(q.params):]...)
+	}
+	return buffer.String(), flat, nil
 }
 
-func (q *query) handleToken(token string) string {
-	return "?"
+// expandArg return the elements of slice and array args, []byte and
+// driver.Valuer are bound as a single value
+func expandArg(arg interface{}) ([]interface{}, bool) {
+	if arg == nil {
+		return nil, false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return nil, false
+	}
+	val := reflect.ValueOf(arg)
+	switch val.Kind() {
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+	case reflect.Array:
+	default:
+		return nil, false
+	}
+	vals := make([]interface{}, val.Len())
+	for i := range vals {
+		vals[i] = val.Index(i).Interface()
+	}
+	return vals, true
+}
+
+func (q *query) handleToken(index int) string {
+	return q.dialect().Placeholder(index)
+}
+
+func (q *query) dialect() Dialect {
+	if q.cfg == nil {
+		return defaultDialect
+	}
+	return q.cfg.dialect
 }
 
 func (q *query) Prepare() error {
 	q.build()
-	var err error
-	q.stmt, err = q.querier.Prepare(q.rawSQL)
+	stmt, err := q.prepare(q.Context(), q.rawSQL)
+	if err == nil {
+		q.stmt, q.stmtSQL = stmt, q.rawSQL
+	}
 	return err
 }
 
+func (q *query) prepare(ctx context.Context, rawSQL string) (*sql.Stmt, error) {
+	var stmt *sql.Stmt
+	err := intercept(ctx, q.cfg, &Event{Op: OpPrepare, Statement: q.sqlType.String(), SQL: rawSQL}, func(ctx context.Context) error {
+		var err error
+		stmt, err = q.querier.PrepareContext(ctx, q.comment(ctx, rawSQL))
+		return err
+	})
+	return stmt, err
+}
+
+// comment append the comment of WithComment to rawSQL, a prepared statement
+// keeps the comment of its prepare
+func (q *query) comment(ctx context.Context, rawSQL string) string {
+	if q.cfg == nil || q.cfg.comment == nil {
+		return rawSQL
+	}
+	return q.cfg.comment.apply(ctx, rawSQL)
+}
+
+// statement return the prepared statement for rawSQL, a statement prepared
+// for another slice length is replaced
+func (q *query) statement(ctx context.Context, rawSQL string) (*sql.Stmt, error) {
+	if q.stmt == nil || q.stmtSQL == rawSQL {
+		return q.stmt, nil
+	}
+	stmt, err := q.prepare(ctx, rawSQL)
+	if err != nil {
+		return nil, err
+	}
+	q.stmt.Close()
+	q.stmt, q.stmtSQL = stmt, rawSQL
+	return stmt, nil
+}
+
 func (q *query) Close() error {
 	if q.stmt != nil {
 		err := q.stmt.Close()
@@ -332,20 +609,31 @@ func (q *query) Close() error {
 
 func (q *query) Args(args ...interface{}) Query {
 	q.args = args
+	q.reflectArgs = nil
+	q.argsErr = nil
 	return q
 }
 
 func (q *query) ReflectArgs(reflectArgs interface{}) Query {
+	q.reflectArgs = reflectArgs
+	q.argsErr = nil
+	q.renderTemplate(reflectArgs)
 	q.build()
 	var args []interface{}
-	if reflectArgs != nil {
+	if reflectArgs != nil && q.argsErr == nil {
 		switch reflect.ValueOf(reflectArgs).Kind() {
 		case reflect.Array, reflect.Slice:
 			args = reflectArgs.([]interface{})
 		default:
-			args = make([]interface{}, 0, len(q.tokens))
-			for _, token := range q.tokens {
-				if val, ok := reflectGetValue(reflectArgs, token); ok {
+			args = make([]interface{}, 0, len(q.params))
+			for _, token := range q.params {
+				if token == "" {
+					args = append(args, nil)
+				} else if val, ok := reflectGetValue(reflectArgs, token); ok {
+					val, err := driverValue(val)
+					if err != nil {
+						q.argsErr = fmt.Errorf("xdb: value of ${%s}: %v", token, err)
+					}
 					args = append(args, val)
 				} else {
 					args = append(args, nil)
@@ -357,28 +645,89 @@ func (q *query) ReflectArgs(reflectArgs interface{}) Query {
 	return q
 }
 
-func (q *query) exec() (sql.Result, error) {
-	if q.stmt != nil {
-		log(q.rawSQL, q.args...)
-		return q.stmt.Exec(q.args...)
+// driverValue unwrap driver.Valuer, a nil pointer Valuer is NULL
+func driverValue(val interface{}) (interface{}, error) {
+	valuer, ok := val.(driver.Valuer)
+	if !ok {
+		return val, nil
+	}
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
 	}
+	return valuer.Value()
+}
+
+func (q *query) bind() (string, []interface{}, error) {
 	q.build()
-	log(q.rawSQL, q.args...)
-	return q.querier.Exec(q.rawSQL, q.args...)
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	if q.upsertErr != nil {
+		return "", nil, q.upsertErr
+	}
+	if q.argsErr != nil {
+		return "", nil, q.argsErr
+	}
+	return q.render(q.args)
+}
+
+func (q *query) exec(ctx context.Context) (sql.Result, error) {
+	rawSQL, args, err := q.bind()
+	if err != nil {
+		return nil, err
+	}
+	log(rawSQL, args...)
+	stmt, err := q.statement(ctx, rawSQL)
+	if err != nil {
+		return nil, err
+	}
+	return interceptExec(ctx, q.cfg, &Event{Op: OpExec, Statement: q.sqlType.String(), SQL: rawSQL, Args: args}, func(ctx context.Context) (sql.Result, error) {
+		if stmt != nil {
+			return stmt.ExecContext(ctx, args...)
+		}
+		return q.querier.ExecContext(ctx, q.comment(ctx, rawSQL), args...)
+	})
 }
 
+// Exec run the statement, outside a transaction it runs again on a
+// retryable error with WithRetry
 func (q *query) Exec() (sql.Result, error) {
-	return q.exec()
+	if !q.retry {
+		return q.exec(q.Context())
+	}
+	var result sql.Result
+	err := retry(q.Context(), q.cfg, func(ctx context.Context) error {
+		var err error
+		result, err = q.exec(ctx)
+		return err
+	})
+	return result, err
 }
 
-func (q *query) rows() (*sql.Rows, error) {
-	if q.stmt != nil {
-		log(q.rawSQL, q.args...)
-		return q.stmt.Query(q.args...)
+// rows run the query, it ends when the rows are closed
+func (q *query) rows() (*interceptedRows, error) {
+	rawSQL, args, err := q.bind()
+	if err != nil {
+		return nil, err
 	}
-	q.build()
-	log(q.rawSQL, q.args...)
-	return q.querier.Query(q.rawSQL, q.args...)
+	log(rawSQL, args...)
+	ctx := q.Context()
+	stmt, err := q.statement(ctx, rawSQL)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{Op: OpQuery, Statement: q.sqlType.String(), SQL: rawSQL, Args: args}
+	ctx, end := startIntercept(ctx, q.cfg, e)
+	var sqlRows *sql.Rows
+	if stmt != nil {
+		sqlRows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		sqlRows, err = q.querier.QueryContext(ctx, q.comment(ctx, rawSQL), args...)
+	}
+	if err != nil {
+		return nil, end(err)
+	}
+	return &interceptedRows{Rows: sqlRows, e: e, end: end}, nil
 }
 
 func (q *query) List(column string) ([]Value, error) {
@@ -495,11 +844,9 @@ func (q *query) Value() (Value, error) {
 }
 
 func (q *query) ReflectRow(row interface{}) error {
-	val := reflect.ValueOf(row)
-	ind := reflect.Indirect(val)
-	typ := ind.Type()
-	if val.Kind() != reflect.Ptr || ind.Kind() != reflect.Struct || typ.String() == "time.Time" {
-		return errors.New("xdb rows must be ptr struct")
+	ind, err := structValue(row)
+	if err != nil {
+		return err
 	}
 
 	sqlRows, err := q.rows()
@@ -508,23 +855,18 @@ func (q *query) ReflectRow(row interface{}) error {
 	}
 	defer sqlRows.Close()
 
-	columns, err := sqlRows.Columns()
+	scanner, err := newRowScanner(sqlRows)
 	if err != nil {
 		return err
 	}
-
-	indexs := getAllColumnFieldIndex(columns, typ)
 	if sqlRows.Next() {
-		cols, err := scanRow(sqlRows, len(columns))
-		if err != nil {
+		if err := scanner.scan(); err != nil {
 			return err
 		}
-		for i, index := range indexs {
-			if index != nil {
-				setFieldValue(ind.FieldByIndex(index), cols[i])
-			}
-		}
-		return nil
+		return scanner.setStruct(ind)
+	}
+	if err := sqlRows.Err(); err != nil {
+		return err
 	}
 	return sql.ErrNoRows
 }
@@ -542,7 +884,7 @@ func (q *query) ReflectRows(rows interface{}) (int64, error) {
 		elType = elType.Elem()
 	}
 
-	if elType.Kind() != reflect.Struct || elType.String() == "time.Time" {
+	if elType.Kind() != reflect.Struct || elType == timeType {
 		return num, errors.New("xdb rows must be ptr struct")
 	}
 
@@ -555,15 +897,12 @@ func (q *query) ReflectRows(rows interface{}) (int64, error) {
 	}
 	defer sqlRows.Close()
 
-	columns, err := sqlRows.Columns()
+	scanner, err := newRowScanner(sqlRows)
 	if err != nil {
 		return num, err
 	}
-
-	indexs := getAllColumnFieldIndex(columns, elType)
 	for sqlRows.Next() {
-		cols, err := scanRow(sqlRows, len(columns))
-		if err != nil {
+		if err := scanner.scan(); err != nil {
 			return int64(0), err
 		}
 		if ind.Len() <= int(num) {
@@ -574,39 +913,85 @@ func (q *query) ReflectRows(rows interface{}) (int64, error) {
 		if elVal.Kind() == reflect.Ptr && elVal.IsNil() {
 			elVal.Set(reflect.New(elVal.Type().Elem()))
 		}
-		elVal = reflect.Indirect(elVal)
-		for i, index := range indexs {
-			if index != nil && len(index) > 0 {
-				setFieldValue(elVal.FieldByIndex(index), cols[i])
-			}
+		if err := scanner.setStruct(reflect.Indirect(elVal)); err != nil {
+			return int64(0), err
 		}
 		num = num + 1
 	}
+	if err := sqlRows.Err(); err != nil {
+		return int64(0), err
+	}
 
 	val.Elem().Set(ind)
 	return num, nil
 }
 
-func scanRow(rows *sql.Rows, colNum int) ([]sql.RawBytes, error) {
+func scanRow(rows *interceptedRows, colNum int) ([]sql.RawBytes, error) {
 	var (
-		vals = make([]sql.RawBytes, colNum)
+		vals = make([]rawValue, colNum)
 		args = make([]interface{}, colNum)
 	)
 	for i := 0; i < len(vals); i++ {
 		args[i] = &vals[i]
 	}
 	err := rows.Scan(args...)
+	cols := make([]sql.RawBytes, colNum)
 	for i := 0; i < len(vals); i++ {
-		if vals[i] != nil {
-			b := make(sql.RawBytes, len(vals[i]))
-			copy(b, vals[i])
-			vals[i] = b
-		}
+		cols[i] = sql.RawBytes(vals[i])
+	}
+	return cols, err
+}
+
+// rawValue scan destination holding a copy of the text form of the column,
+// unlike sql.RawBytes an empty value is kept apart from NULL (nil)
+type rawValue []byte
+
+func (r *rawValue) Scan(src interface{}) error {
+	buf := (*r)[:0]
+	if buf == nil {
+		buf = []byte{}
+	}
+	switch v := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		buf = append(buf, v...)
+	case string:
+		buf = append(buf, v...)
+	case int64:
+		buf = strconv.AppendInt(buf, v, 10)
+	case float64:
+		buf = strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case bool:
+		buf = strconv.AppendBool(buf, v)
+	case time.Time:
+		buf = v.AppendFormat(buf, time.RFC3339Nano)
+	default:
+		buf = append(buf, fmt.Sprint(v)...)
 	}
-	return vals, err
+	*r = buf
+	return nil
 }
 
-func setFieldValue(val reflect.Value, bytes sql.RawBytes) {
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// setFieldValue set val from the column bytes, NULL sets pointer fields to nil
+// and a non-NULL value allocates them
+func setFieldValue(val reflect.Value, bytes sql.RawBytes) error {
+	if val.Kind() == reflect.Ptr {
+		if bytes == nil {
+			val.Set(reflect.Zero(val.Type()))
+			return nil
+		}
+		if val.IsNil() {
+			val.Set(reflect.New(val.Type().Elem()))
+		}
+		return setFieldValue(val.Elem(), bytes)
+	}
+	if val.CanAddr() && val.Addr().Type().Implements(scannerType) {
+		return scanFieldValue(val.Addr().Interface().(sql.Scanner), bytes)
+	}
 	switch val.Kind() {
 	case reflect.Bool:
 		var v = false
@@ -636,18 +1021,31 @@ func setFieldValue(val reflect.Value, bytes sql.RawBytes) {
 		val.SetFloat(v)
 	case reflect.Struct:
 		if _, ok := val.Interface().(time.Time); ok {
-			str := string(bytes)
-			if len(str) >= 19 {
-				t, _ := time.Parse("2006-01-02 15:04:05", str[:19])
-				val.Set(reflect.ValueOf(t))
-			} else if len(str) >= 10 {
-				t, _ := time.Parse("2006-01-02", str[:10])
+			if t, err := parseTime(string(bytes)); err == nil {
 				val.Set(reflect.ValueOf(t))
 			}
 		}
 	case reflect.Interface:
-		val.Set(reflect.ValueOf(bytes))
+		val.Set(reflect.ValueOf(append(sql.RawBytes(nil), bytes...)))
+	}
+	return nil
+}
+
+// scanFieldValue scan the column bytes into scanner, time text falls back to
+// time.Time for scanners such as sql.NullTime
+func scanFieldValue(scanner sql.Scanner, bytes sql.RawBytes) error {
+	if bytes == nil {
+		return scanner.Scan(nil)
 	}
+	b := make([]byte, len(bytes))
+	copy(b, bytes)
+	err := scanner.Scan(b)
+	if err != nil {
+		if t, e := parseTime(string(bytes)); e == nil && scanner.Scan(t) == nil {
+			return nil
+		}
+	}
+	return err
 }
 
 func getAllColumnFieldIndex(columns []string, typ reflect.Type) [][]int {
@@ -661,87 +1059,119 @@ func getAllColumnFieldIndex(columns []string, typ reflect.Type) [][]int {
 var timeType = reflect.TypeOf(time.Time{})
 
 func getColumnFieldIndex(column string, typ reflect.Type) []int {
-	if typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-	}
-	if typ.Kind() != reflect.Struct || typ.ConvertibleTo(timeType) {
-		return nil
-	}
-	for i := 0; i < typ.NumField(); i++ {
-		f := typ.Field(i)
-		if f.Anonymous {
-			index := getColumnFieldIndex(column, f.Type)
-			if index != nil && len(index) > 0 {
-				return append([]int{i}, index...)
-			}
-		}
-		if f.Tag.Get(tagName) == column {
-			return []int{i}
-		}
-		if column == f.Name {
-			return []int{i}
-		}
-
-	}
-	return nil
+	return getStructInfo(typ).columns[column]
 }
 
-func ensureValueFieldIndex(val reflect.Value, index []int) {
-	for _, i := range index {
-		val = val.Field(i)
-		if val.Kind() == reflect.Ptr {
+// ensureValueFieldIndex return the field of val at index, nil embedded
+// pointer structs on the way are allocated. invalid when one can't be set
+func ensureValueFieldIndex(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
 			if val.IsNil() {
+				if !val.CanSet() {
+					return reflect.Value{}
+				}
 				val.Set(reflect.New(val.Type().Elem()))
 			}
 			val = val.Elem()
 		}
+		val = val.Field(x)
 	}
+	return val
 }
 
+// reflectGetValue get the value of property from root, property is a
+// dotted path through nested structs, pointers and maps with optional
+// indexes, eg. User.Address.City, filter.status, Items[0].ID
 func reflectGetValue(root interface{}, property string) (interface{}, bool) {
-	ok := true
 	rootValue := reflect.ValueOf(root)
 	if rootValue.Kind() == reflect.Ptr {
 		rootValue = rootValue.Elem()
 	}
-	var result interface{}
-	var value reflect.Value
 	switch rootValue.Kind() {
-	case reflect.Map:
-		key := reflect.ValueOf(property)
-		value = rootValue.MapIndex(key)
-		break
-	case reflect.Struct:
-		rootTyp := rootValue.Type()
-		for i := 0; i < rootTyp.NumField(); i++ {
-			typ := rootTyp.Field(i)
-			if typ.Anonymous {
-				if inf, ok := reflectGetValue(rootValue.Field(i).Interface(), property); ok {
-					return inf, ok
-				}
-			}
-			str, ok := typ.Tag.Lookup(tagName)
-			if ok && str == property {
-				value = rootValue.Field(i)
-				break
-			}
-			if !ok && typ.Name == property {
-				value = rootValue.Field(i)
-				break
-			}
-		}
-		break
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
 	default:
 		panic(fmt.Sprintf("%v is not (map, *map, struct, *struct)", root))
 	}
 
-	if value.IsValid() {
-		result = value.Interface()
-		ok = true
-	} else {
-		result = nil
-		ok = false
+	value := rootValue
+	for _, name := range strings.Split(property, ".") {
+		var indexs []string
+		if i := strings.IndexByte(name, '['); i != -1 && strings.HasSuffix(name, "]") {
+			indexs = strings.Split(name[i+1:len(name)-1], "][")
+			name = name[:i]
+		}
+		if name != "" {
+			value = reflectProperty(value, name)
+		}
+		for _, index := range indexs {
+			value = reflectIndex(value, index)
+		}
+		if !value.IsValid() {
+			return nil, false
+		}
+	}
+
+	if !value.CanInterface() {
+		return nil, false
+	}
+	return value.Interface(), true
+}
+
+func reflectIndirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func reflectMapIndex(value reflect.Value, key string) reflect.Value {
+	keyType := value.Type().Key()
+	var keyValue reflect.Value
+	switch keyType.Kind() {
+	case reflect.String, reflect.Interface:
+		keyValue = reflect.ValueOf(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return reflect.Value{}
+		}
+		keyValue = reflect.ValueOf(n)
+	default:
+		return reflect.Value{}
 	}
+	return value.MapIndex(keyValue.Convert(keyType))
+}
 
-	return result, ok
+// reflectProperty get field or map key name of value
+func reflectProperty(value reflect.Value, name string) reflect.Value {
+	value = reflectIndirect(value)
+	switch value.Kind() {
+	case reflect.Map:
+		return reflectMapIndex(value, name)
+	case reflect.Struct:
+		if index, ok := getStructInfo(value.Type()).properties[name]; ok {
+			return fieldByIndex(value, index)
+		}
+	}
+	return reflect.Value{}
+}
+
+// reflectIndex get element index of slice, array or map value
+func reflectIndex(value reflect.Value, index string) reflect.Value {
+	value = reflectIndirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= value.Len() {
+			return reflect.Value{}
+		}
+		return value.Index(i)
+	case reflect.Map:
+		return reflectMapIndex(value, strings.Trim(index, "\"'"))
+	}
+	return reflect.Value{}
 }
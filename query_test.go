@@ -1,9 +1,14 @@
 package xdb
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,6 +43,13 @@ func TestQuery(t *testing.T) {
 	t.Run("Select", _TestSelect)
 	t.Run("Update", _TestUpdate)
 	t.Run("Delete", _TestDelete)
+	t.Run("Context", _TestContext)
+	t.Run("In", _TestIn)
+	t.Run("NestedArgs", _TestNestedArgs)
+	t.Run("Null", _TestNull)
+	t.Run("Scanner", _TestScanner)
+	t.Run("Pointer", _TestPointer)
+	t.Run("Each", _TestEach)
 }
 
 var _InitTable = func(t *testing.T) {
@@ -152,3 +164,333 @@ var _TestDelete = func(t *testing.T) {
 	}
 	fmt.Println(val)
 }
+
+var _TestContext = func(t *testing.T) {
+	var (
+		val Value
+		err error
+	)
+
+	val, err = ndb.NewQuery().WithContext(context.Background()).Select("count(*)").From("user").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(val)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ndb.NewQuery().WithContext(ctx).Select("count(*)").From("user").Value()
+	if err != context.Canceled {
+		t.Fatal("expect context canceled, got", err)
+	}
+
+	tx, err := ndb.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	_, err = tx.NewQuery().WithContext(ctx).Update("user").Set("departname = ?").Args("ops").Exec()
+	if err != context.Canceled {
+		t.Fatal("expect context canceled, got", err)
+	}
+}
+
+var _TestIn = func(t *testing.T) {
+	var (
+		val Value
+		err error
+	)
+
+	val, err = ndb.NewQuery().Select("count(*)").From("user").Where("id in (${Ids})").ReflectArgs(map[string]interface{}{"Ids": []int64{1, 2, 3}}).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Int() != 3 {
+		t.Fatal("expect 3, got", val)
+	}
+
+	val, err = ndb.NewQuery().Select("count(*)").From("user").Where("id in (${Ids}) and departname = ${Depart}").ReflectArgs(map[string]interface{}{"Ids": [2]int{4, 5}, "Depart": "dev"}).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Int() != 2 {
+		t.Fatal("expect 2, got", val)
+	}
+
+	for _, where := range []string{"id in (${Ids})", "id not in (${Ids})"} {
+		_, err = ndb.NewQuery().Select("count(*)").From("user").Where(where).ReflectArgs(map[string]interface{}{"Ids": []int64{}}).Value()
+		if err == nil || !strings.Contains(err.Error(), "empty slice") {
+			t.Fatal("expect empty slice error, got", err)
+		}
+	}
+
+	q := ndb.NewQuery().Select("count(*)").From("user").Where("id in (${Ids})")
+	if err = q.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	for i, ids := range [][]int64{{1}, {1, 2}, {1, 2, 3, 4}} {
+		val, err = q.ReflectArgs(map[string]interface{}{"Ids": ids}).Value()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if val.Int() != int64(len(ids)) {
+			t.Fatal(i, "expect", len(ids), "got", val)
+		}
+	}
+
+	pq := newQuery(nil, newConfig([]Option{WithDialect(Postgres)}))
+	pq.SQL("select * from user where id in (${Ids}) and name = ${Name}").ReflectArgs(map[string]interface{}{"Ids": []int{7, 8}, "Name": "x"})
+	rawSQL, args, _ := pq.bind()
+	if expect := "select * from user where id in ($1, $2) and name = $3"; rawSQL != expect || len(args) != 3 {
+		t.Fatalf("expect %q, got %q %v", expect, rawSQL, args)
+	}
+
+	pq = newQuery(nil, newConfig([]Option{WithDialect(Postgres)}))
+	pq.SQL("update t set tags = ? where id = ?").Args([]string{"a", "b"}, 1)
+	rawSQL, args, _ = pq.bind()
+	if expect := "update t set tags = $1 where id = $2"; rawSQL != expect || len(args) != 2 {
+		t.Fatalf("expect %q, got %q %v", expect, rawSQL, args)
+	}
+}
+
+var _TestNestedArgs = func(t *testing.T) {
+	type Address struct {
+		City string `db:"city"`
+	}
+	type Item struct {
+		ID int64
+	}
+	type Base struct {
+		Depart string
+	}
+	type User struct {
+		*Base
+		Address *Address
+		Items   []Item
+		Filter  map[string]interface{} `db:"filter"`
+	}
+	args := struct {
+		User User
+	}{User{
+		Base:    &Base{Depart: "dev"},
+		Address: &Address{City: "hangzhou"},
+		Items:   []Item{{ID: 1}, {ID: 2}},
+		Filter:  map[string]interface{}{"status": 1, "ids": []int64{3, 4}},
+	}}
+
+	cases := map[string]interface{}{
+		"User.Depart":         "dev",
+		"User.Address.city":   "hangzhou",
+		"User.Items[1].ID":    int64(2),
+		"User.filter.status":  1,
+		"User.filter.ids[1]":  int64(4),
+		"User.filter[status]": 1,
+	}
+	for path, expect := range cases {
+		val, ok := reflectGetValue(&args, path)
+		if !ok || val != expect {
+			t.Fatalf("%s: expect %v, got %v %v", path, expect, val, ok)
+		}
+	}
+	for _, path := range []string{"User.Address.Street", "User.Items[2].ID", "User.filter.none", "User.Depart.Name"} {
+		if val, ok := reflectGetValue(&args, path); ok {
+			t.Fatalf("%s: expect not found, got %v", path, val)
+		}
+	}
+	if _, ok := reflectGetValue(struct{ User User }{}, "User.Depart"); ok {
+		t.Fatal("expect nil embedded pointer not found")
+	}
+
+	val, err := ndb.NewQuery().Select("count(*)").From("user").Where("id in (${User.Items[0].ID}, ${User.filter.ids}) and departname = ${User.Depart}").ReflectArgs(args).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Int() != 3 {
+		t.Fatal("expect 3, got", val)
+	}
+}
+
+var _TestNull = func(t *testing.T) {
+	row, err := ndb.NewQuery().SQL("select NULL as n, '' as e, 12 as i, 1.5 as f, '2017-10-01 08:00:00' as d").Row()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !row.Get("n").IsNull() || row.Get("e").IsNull() || row.Get("e").String() != "" {
+		t.Fatal("expect NULL and empty string distinct", row)
+	}
+	if _, err = row.Get("n").IntE(); err != ErrNullValue {
+		t.Fatal("expect ErrNullValue, got", err)
+	}
+	if _, err = row.Get("e").IntE(); err == nil {
+		t.Fatal("expect parse error for empty string")
+	}
+	if i, err := row.Get("i").IntE(); err != nil || i != 12 {
+		t.Fatal("expect 12, got", i, err)
+	}
+	if f, err := row.Get("f").FloatE(); err != nil || f != 1.5 {
+		t.Fatal("expect 1.5, got", f, err)
+	}
+	if d, err := row.Get("d").TimeE(); err != nil || d.Format("2006-01-02 15:04:05") != "2017-10-01 08:00:00" {
+		t.Fatal("expect 2017-10-01 08:00:00, got", d, err)
+	}
+	if row.Get("n").NullInt64().Valid || !row.Get("i").NullInt64().Valid || !row.Get("e").NullString().Valid {
+		t.Fatal("unexpected null values", row)
+	}
+	if _, err = row.GetE("none"); err != ErrColumnNotFound {
+		t.Fatal("expect ErrColumnNotFound, got", err)
+	}
+	if v, err := row.GetE("n"); err != nil || !v.IsNull() {
+		t.Fatal("expect NULL value, got", v, err)
+	}
+}
+
+// money cents stored as "12.34" text
+type money int64
+
+func (m *money) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("money: can't scan %T", src)
+	}
+	parts := strings.SplitN(string(b), ".", 2)
+	yuan, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	var cents int64
+	if len(parts) == 2 {
+		if cents, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return err
+		}
+	}
+	*m = money(yuan*100 + cents)
+	return nil
+}
+
+func (m money) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d.%02d", m/100, m%100), nil
+}
+
+var _TestScanner = func(t *testing.T) {
+	type Entity struct {
+		ID       int64          `db:"id"`
+		Username sql.NullString `db:"username"`
+		Depart   sql.NullString `db:"departname"`
+		Balance  money          `db:"balance"`
+		Created  sql.NullTime   `db:"created"`
+	}
+
+	e := &Entity{Balance: 1234}
+	val, err := ndb.NewQuery().SQL("select ${balance}").ReflectArgs(e).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.String() != "12.34" {
+		t.Fatal("expect 12.34, got", val)
+	}
+
+	err = ndb.NewQuery().SQL("select id, username, NULL as departname, ${balance} as balance, '2017-10-01 08:00:00' as created from user where id = 1").ReflectArgs(e).ReflectRow(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Username.Valid || e.Depart.Valid || e.Balance != 1234 || !e.Created.Valid || e.Created.Time.Year() != 2017 {
+		t.Fatalf("unexpected entity %+v", e)
+	}
+
+	var es []Entity
+	_, err = ndb.NewQuery().SQL("select 'abc' as balance").ReflectRows(&es)
+	if err == nil {
+		t.Fatal("expect scan error")
+	}
+}
+
+var _TestPointer = func(t *testing.T) {
+	type Detail struct {
+		Intro   *string `db:"intro"`
+		Profile string  `db:"profile"`
+	}
+	type Entity struct {
+		*Detail
+		ID       *int64          `db:"id"`
+		Username *string         `db:"username"`
+		Depart   *string         `db:"departname"`
+		Created  *time.Time      `db:"created"`
+		Nullable *sql.NullString `db:"nullable"`
+	}
+
+	e := &Entity{Depart: new(string)}
+	err := ndb.NewQuery().SQL("select id, username, NULL as departname, '2017-10-01' as created, 'hi' as intro, NULL as nullable from user where id = 1").ReflectRow(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == nil || *e.ID != 1 || e.Username == nil || e.Depart != nil || e.Created == nil || e.Created.Year() != 2017 || e.Nullable != nil {
+		t.Fatalf("unexpected entity %+v", e)
+	}
+	if e.Detail == nil || e.Detail.Intro == nil || *e.Detail.Intro != "hi" {
+		t.Fatalf("unexpected detail %+v", e.Detail)
+	}
+
+	var es []*Entity
+	_, err = ndb.NewQuery().SQL("select id, 'x' as nullable from user where id < 3").ReflectRows(&es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 || es[1].ID == nil || *es[1].ID != 2 || es[1].Detail != nil || es[1].Nullable == nil || es[1].Nullable.String != "x" {
+		t.Fatalf("unexpected entities %+v", es)
+	}
+}
+
+var _TestEach = func(t *testing.T) {
+	var (
+		rows []Row
+		err  error
+	)
+	err = ndb.NewQuery().Select("id, username").From("user").OrderBy("id").Each(func(row Row) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || rows[0].Get("id").Int() != 1 || rows[4].Get("username").String() != "mingo-4" {
+		t.Fatal("unexpected rows", rows)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = ndb.NewQuery().Select("id").From("user").Each(func(row Row) error {
+		n++
+		if n == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 2 {
+		t.Fatal("expect stop after 2 rows, got", err, n)
+	}
+
+	type Entity struct {
+		ID       int64  `db:"id"`
+		Username string `db:"username"`
+	}
+	it, err := ndb.NewQuery().Select("id, username").From("user").OrderBy("id").Iterate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var es []Entity
+	for it.Next() {
+		var e Entity
+		if err = it.ScanStruct(&e); err != nil {
+			t.Fatal(err)
+		}
+		es = append(es, e)
+	}
+	if err = it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 5 || es[0].Username != "mingo-0" || es[4].Username != "mingo-4" {
+		t.Fatal("unexpected entities", es)
+	}
+}
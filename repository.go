@@ -0,0 +1,191 @@
+package xdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrNoPrimaryKey entity has no field tagged pk
+var ErrNoPrimaryKey = errors.New("xdb: no primary key")
+
+// Repository crud of table mapped to the struct T by the db tag, see
+// fieldInfo for the tag options
+type Repository[T any] struct {
+	helper Helper
+	table  string
+	info   *structInfo
+}
+
+// NewRepository new repository of table on a DB or TX
+func NewRepository[T any](h Helper, table string) *Repository[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if !isStructType(typ) || typ.Kind() == reflect.Ptr {
+		panic(fmt.Sprintf("xdb: repository of %s, expect struct", typ))
+	}
+	return &Repository[T]{
+		helper: h,
+		table:  table,
+		info:   getStructInfo(typ),
+	}
+}
+
+// WithHelper copy of the repository on h, eg. a TX
+func (r *Repository[T]) WithHelper(h Helper) *Repository[T] {
+	return &Repository[T]{helper: h, table: r.table, info: r.info}
+}
+
+// Table table name
+func (r *Repository[T]) Table() string {
+	return r.table
+}
+
+// Insert entity, a zero autoincr primary key is left to the database and
+// set from LastInsertId, it stays zero when the driver has no LastInsertId
+// as the row is inserted anyway. an entity without any column to insert is inserted
+// with the default values of the dialect
+func (r *Repository[T]) Insert(entity *T) (sql.Result, error) {
+	val := reflect.ValueOf(entity).Elem()
+	var (
+		columns  []string
+		args     []interface{}
+		autoIncr *fieldInfo
+	)
+	for _, f := range r.info.fields {
+		field := fieldByIndex(val, f.index)
+		if f.readonly || !field.IsValid() {
+			continue
+		}
+		if field.IsZero() && (f.autoIncr || f.omitEmpty) {
+			if f.autoIncr {
+				autoIncr = f
+			}
+			continue
+		}
+		columns = append(columns, f.column)
+		args = append(args, field.Interface())
+	}
+
+	q := r.helper.NewQuery()
+	if len(columns) == 0 {
+		insert := r.helper.Dialect().InsertDefaults(r.table)
+		if insert == "" {
+			return nil, fmt.Errorf("xdb: no column to insert %s", r.table)
+		}
+		q.SQL(insert)
+	} else {
+		q.InsertInto(r.table).Columns(strings.Join(columns, ", ")).Values(placeholders(len(columns))).Args(args...)
+	}
+	result, err := q.Exec()
+	if err != nil || autoIncr == nil {
+		return result, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return result, nil
+	}
+	field := ensureValueFieldIndex(val, autoIncr.index)
+	if err := setFieldValue(field, sql.RawBytes(fmt.Sprint(id))); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// InsertBatch insert entities with BatchInsert, autoincr primary keys are
+// not filled
+func (r *Repository[T]) InsertBatch(entities []*T) (int64, error) {
+	return BatchInsert(r.helper, r.table, entities)
+}
+
+// Update the columns of entity by primary key
+func (r *Repository[T]) Update(entity *T) (sql.Result, error) {
+	val := reflect.ValueOf(entity).Elem()
+	where, whereArgs, err := r.pkCondition(val)
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.helper.NewQuery().Update(r.table)
+	var args []interface{}
+	for _, f := range r.info.fields {
+		field := fieldByIndex(val, f.index)
+		if f.pk || f.autoIncr || f.readonly || !field.IsValid() || (f.omitEmpty && field.IsZero()) {
+			continue
+		}
+		q.Set(f.column + " = ?")
+		args = append(args, field.Interface())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("xdb: no column to update %s", r.table)
+	}
+	return q.Where(where).Args(append(args, whereArgs...)...).Exec()
+}
+
+// Delete entity by primary key
+func (r *Repository[T]) Delete(entity *T) (sql.Result, error) {
+	where, args, err := r.pkCondition(reflect.ValueOf(entity).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return r.helper.NewQuery().DeleteFrom(r.table).Where(where).Args(args...).Exec()
+}
+
+// FindByPK find entity by primary key values in field order, sql.ErrNoRows
+// when not found
+func (r *Repository[T]) FindByPK(pk ...interface{}) (*T, error) {
+	var where []string
+	for _, f := range r.info.fields {
+		if f.pk {
+			where = append(where, f.column+" = ?")
+		}
+	}
+	if len(where) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+	if len(pk) != len(where) {
+		return nil, fmt.Errorf("xdb: %d primary key values, expect %d", len(pk), len(where))
+	}
+
+	entity := new(T)
+	err := r.helper.NewQuery().Select(strings.Join(r.columns(), ", ")).From(r.table).Where(strings.Join(where, " and ")).Args(pk...).ReflectRow(entity)
+	if err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (r *Repository[T]) columns() []string {
+	columns := make([]string, len(r.info.fields))
+	for i, f := range r.info.fields {
+		columns[i] = f.column
+	}
+	return columns
+}
+
+func (r *Repository[T]) pkCondition(val reflect.Value) (string, []interface{}, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	for _, f := range r.info.fields {
+		if !f.pk {
+			continue
+		}
+		field := fieldByIndex(val, f.index)
+		if !field.IsValid() {
+			return "", nil, fmt.Errorf("xdb: primary key %s is nil", f.column)
+		}
+		where = append(where, f.column+" = ?")
+		args = append(args, field.Interface())
+	}
+	if len(where) == 0 {
+		return "", nil, ErrNoPrimaryKey
+	}
+	return strings.Join(where, " and "), args, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,137 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+type repoBase struct {
+	ID int64 `db:"id,pk,autoincr"`
+}
+
+type repoUser struct {
+	repoBase
+	Username string         `db:"username"`
+	Depart   string         `db:"departname,omitempty"`
+	Intro    sql.NullString `db:"intro"`
+	Created  string         `db:"created,readonly"`
+	Cache    string         `db:"-"`
+}
+
+func TestRepository(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `repo_user` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `username` VARCHAR(64) NULL, `departname` VARCHAR(64) NOT NULL DEFAULT 'none', `intro` TEXT NULL, `created` STRING NOT NULL DEFAULT 'now')").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `repo_user`").Exec()
+
+	repo := NewRepository[repoUser](ndb, "repo_user")
+	u := &repoUser{Username: "mingo", Created: "ignored", Cache: "ignored"}
+	if _, err := repo.Insert(u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 {
+		t.Fatal("expect id 1, got", u.ID)
+	}
+
+	found, err := repo.FindByPK(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Username != "mingo" || found.Depart != "none" || found.Intro.Valid || found.Created != "now" || found.Cache != "" {
+		t.Fatalf("unexpected user %+v", found)
+	}
+
+	found.Depart = "dev"
+	found.Intro = sql.NullString{String: "hi", Valid: true}
+	found.Created = "ignored"
+	if _, err = repo.Update(found); err != nil {
+		t.Fatal(err)
+	}
+	found, err = repo.FindByPK(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Depart != "dev" || found.Intro.String != "hi" || found.Created != "now" {
+		t.Fatalf("unexpected user %+v", found)
+	}
+
+	tx, err := ndb.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.WithHelper(tx).Delete(found); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.FindByPK(u.ID); err != sql.ErrNoRows {
+		t.Fatal("expect sql.ErrNoRows, got", err)
+	}
+
+	type noPK struct {
+		Name string
+	}
+	if _, err = NewRepository[noPK](ndb, "repo_user").Delete(&noPK{}); err != ErrNoPrimaryKey {
+		t.Fatal("expect ErrNoPrimaryKey, got", err)
+	}
+
+	type defaults struct {
+		repoBase
+		Depart string `db:"departname,omitempty"`
+	}
+	d := &defaults{}
+	if _, err = NewRepository[defaults](New(db, WithDialect(SQLite)), "repo_user").Insert(d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != 2 {
+		t.Fatal("expect id 2, got", d.ID)
+	}
+	noID := &defaults{}
+	helper := &querierHelper{querier: noLastIDQuerier{db}, cfg: newConfig([]Option{WithDialect(SQLite)})}
+	if _, err = NewRepository[defaults](helper, "repo_user").Insert(noID); err != nil {
+		t.Fatal("expect the insert without LastInsertId to succeed, got", err)
+	}
+	if noID.ID != 0 {
+		t.Fatal("expect id unset, got", noID.ID)
+	}
+	for _, h := range []Helper{ndb, New(db, WithDialect(Oracle))} {
+		if _, err = NewRepository[defaults](h, "repo_user").Insert(&defaults{}); err == nil {
+			t.Fatal("expect no column error")
+		}
+	}
+}
+
+// querierHelper Helper of a Querier
+type querierHelper struct {
+	querier Querier
+	cfg     *config
+}
+
+func (h *querierHelper) NewQuery() Query  { return newQuery(h.querier, h.cfg) }
+func (h *querierHelper) Querier() Querier { return h.querier }
+func (h *querierHelper) Dialect() Dialect { return h.cfg.dialect }
+
+// noLastIDQuerier Querier whose results have no LastInsertId, like the
+// postgres drivers
+type noLastIDQuerier struct {
+	*sql.DB
+}
+
+type noLastIDResult struct {
+	sql.Result
+}
+
+func (q noLastIDQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := q.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return noLastIDResult{result}, nil
+}
+
+func (noLastIDResult) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported")
+}
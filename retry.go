@@ -0,0 +1,166 @@
+package xdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RetryPolicy rerun of DB.Transact and of Exec outside a transaction when they
+// fail with a retryable error, eg. a deadlock or a serialization failure
+type RetryPolicy struct {
+	// Attempts max runs including the first, no retry below 2
+	Attempts int
+	// Backoff delay before the second run, doubled before every next run,
+	// default 10ms
+	Backoff time.Duration
+	// MaxBackoff cap of the delay, default 1s
+	MaxBackoff time.Duration
+	// Retryable classify the error, default the Retryable of the dialect
+	Retryable func(err error) bool
+}
+
+// WithRetry set the retry policy of DB.Transact and standalone Exec
+func WithRetry(policy RetryPolicy) Option {
+	return func(cfg *config) {
+		if policy.Backoff <= 0 {
+			policy.Backoff = 10 * time.Millisecond
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = time.Second
+		}
+		cfg.retry = &policy
+	}
+}
+
+// delay backoff before the attempt-th (1-based) rerun, with jitter in
+// [delay/2, delay]
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retry run fn until it succeeds, fails with an error not retryable or the
+// attempts of cfg run out, the ctx passed to fn carries the attempt for the
+// Event
+func retry(ctx context.Context, cfg *config, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	if cfg == nil || cfg.retry == nil {
+		return err
+	}
+	policy := cfg.retry
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = cfg.dialect.Retryable
+	}
+	for attempt := 1; attempt < policy.Attempts && err != nil && retryable(err); attempt++ {
+		delay := policy.delay(attempt)
+		log(fmt.Sprintf("xdb: retry %d/%d in %s: %v", attempt+1, policy.Attempts, delay, err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn(withAttempt(ctx, attempt+1))
+	}
+	return err
+}
+
+// walkError call fn on err and the errors it wraps until fn returns true
+func walkError(err error, fn func(err error) bool) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if fn(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasErrorNumber report whether err wraps a driver error whose Number field,
+// as in the mysql and sqlserver drivers, is one of numbers
+func hasErrorNumber(err error, numbers ...int64) bool {
+	return walkError(err, func(err error) bool {
+		val := reflectIndirect(reflect.ValueOf(err))
+		if val.Kind() != reflect.Struct {
+			return false
+		}
+		field := val.FieldByName("Number")
+		var n int64
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = field.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = int64(field.Uint())
+		default:
+			return false
+		}
+		for _, number := range numbers {
+			if n == number {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// hasSQLState report whether err wraps a driver error with one of the
+// SQLSTATE codes, read from a SQLState method or a string Code field
+func hasSQLState(err error, states ...string) bool {
+	return walkError(err, func(err error) bool {
+		var state string
+		if e, ok := err.(interface{ SQLState() string }); ok {
+			state = e.SQLState()
+		} else if val := reflectIndirect(reflect.ValueOf(err)); val.Kind() == reflect.Struct {
+			if field := val.FieldByName("Code"); field.Kind() == reflect.String {
+				state = field.String()
+			}
+		}
+		for _, s := range states {
+			if state == s {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func hasErrorMessage(err error, messages ...string) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, m := range messages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// mysql deadlock and lock wait timeout
+func mysqlRetryable(err error) bool {
+	return hasErrorNumber(err, 1213, 1205) || hasSQLState(err, "40001")
+}
+
+// postgres serialization failure and deadlock
+func postgresRetryable(err error) bool {
+	return hasSQLState(err, "40001", "40P01")
+}
+
+// sqlite busy and locked
+func sqliteRetryable(err error) bool {
+	return hasErrorMessage(err, "database is locked", "database table is locked")
+}
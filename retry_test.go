@@ -0,0 +1,104 @@
+package xdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+type pgError struct {
+	Code string
+}
+
+func (e *pgError) Error() string { return "pq: " + e.Code }
+
+func TestRetryable(t *testing.T) {
+	deadlock := &mysqlError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	cases := []struct {
+		dialect Dialect
+		err     error
+		expect  bool
+	}{
+		{MySQL, deadlock, true},
+		{MySQL, fmt.Errorf("update user: %w", deadlock), true},
+		{MySQL, &mysqlError{Number: 1062}, false},
+		{Postgres, &pgError{Code: "40001"}, true},
+		{Postgres, &pgError{Code: "40P01"}, true},
+		{Postgres, &pgError{Code: "23505"}, false},
+		{SQLite, errors.New("database is locked"), true},
+		{SQLServer, &mysqlError{Number: 1205}, true},
+		{Oracle, errors.New("ORA-08177: can't serialize access for this transaction"), true},
+		{defaultDialect, &pgError{Code: "40P01"}, true},
+		{defaultDialect, errors.New("no such table"), false},
+	}
+	for i, c := range cases {
+		if c.dialect.Retryable(c.err) != c.expect {
+			t.Fatalf("case %d %s: expect retryable %v of %v", i, c.dialect.Name(), c.expect, c.err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var logs []string
+	defer func(logFunc func(sql string, args ...interface{})) { LogFunc = logFunc }(LogFunc)
+	LogFunc = func(sql string, args ...interface{}) {
+		logs = append(logs, sql)
+	}
+
+	rdb := New(db, WithRetry(RetryPolicy{Attempts: 3, Backoff: time.Millisecond}))
+	deadlock := &mysqlError{Number: 1213}
+
+	runs := 0
+	err := rdb.Transact(context.Background(), nil, func(tx TX) error {
+		runs++
+		if runs < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	if err != nil || runs != 3 {
+		t.Fatal("expect 3 runs, got", runs, err)
+	}
+	retries := 0
+	for _, l := range logs {
+		if strings.HasPrefix(l, "xdb: retry") {
+			retries++
+		}
+	}
+	if retries != 2 {
+		t.Fatal("expect 2 retries logged, got", logs)
+	}
+
+	runs = 0
+	err = rdb.Transact(context.Background(), nil, func(tx TX) error {
+		runs++
+		return deadlock
+	})
+	if err != deadlock || runs != 3 {
+		t.Fatal("expect 3 runs and deadlock, got", runs, err)
+	}
+
+	runs = 0
+	errFail := errors.New("fail")
+	err = rdb.Transact(context.Background(), nil, func(tx TX) error {
+		runs++
+		return errFail
+	})
+	if err != errFail || runs != 1 {
+		t.Fatal("expect no retry, got", runs, err)
+	}
+
+	if _, err := rdb.NewQuery().SQL("select * from no_such_table").Exec(); err == nil {
+		t.Fatal("expect error")
+	}
+}
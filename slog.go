@@ -0,0 +1,114 @@
+package xdb
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlogOptions options of the slog interceptor
+type SlogOptions struct {
+	// Level of a successful operation, default slog.LevelDebug
+	Level slog.Leveler
+	// SlowLevel of a successful operation taking SlowThreshold or more,
+	// default slog.LevelWarn
+	SlowLevel slog.Leveler
+	// SlowThreshold 0 never slow
+	SlowThreshold time.Duration
+	// ErrorLevel of a failed operation, default slog.LevelError
+	ErrorLevel slog.Leveler
+	// Args log the bind arguments, off as they may be sensitive
+	Args bool
+}
+
+type slogInterceptor struct {
+	logger *slog.Logger
+	opts   SlogOptions
+}
+
+// NewSlogInterceptor Interceptor logging every event to logger with the op,
+// statement, sql, duration, rows, attempt, error and caller attributes
+func NewSlogInterceptor(logger *slog.Logger, opts SlogOptions) Interceptor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts.Level == nil {
+		opts.Level = slog.LevelDebug
+	}
+	if opts.SlowLevel == nil {
+		opts.SlowLevel = slog.LevelWarn
+	}
+	if opts.ErrorLevel == nil {
+		opts.ErrorLevel = slog.LevelError
+	}
+	return &slogInterceptor{logger: logger, opts: opts}
+}
+
+func (s *slogInterceptor) Before(ctx context.Context, e *Event) context.Context {
+	return ctx
+}
+
+func (s *slogInterceptor) After(ctx context.Context, e *Event) {
+	level := s.opts.Level.Level()
+	switch {
+	case e.Err != nil:
+		level = s.opts.ErrorLevel.Level()
+	case s.opts.SlowThreshold > 0 && e.Duration >= s.opts.SlowThreshold:
+		level = s.opts.SlowLevel.Level()
+	}
+	if !s.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 9)
+	attrs = append(attrs, slog.String("op", string(e.Op)))
+	if e.Statement != "" {
+		attrs = append(attrs, slog.String("statement", e.Statement), slog.String("sql", e.SQL))
+	}
+	if s.opts.Args && len(e.Args) > 0 {
+		attrs = append(attrs, slog.Any("args", e.Args))
+	}
+	attrs = append(attrs, slog.Duration("duration", e.Duration))
+	if e.RowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64("rows", e.RowsAffected))
+	} else if e.RowsRead >= 0 {
+		attrs = append(attrs, slog.Int64("rows", e.RowsRead))
+	}
+	if e.Attempt > 1 {
+		attrs = append(attrs, slog.Int("attempt", e.Attempt))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	if caller := callerOutside(); caller != "" {
+		attrs = append(attrs, slog.String("caller", caller))
+	}
+	s.logger.LogAttrs(ctx, level, "xdb "+string(e.Op), attrs...)
+}
+
+// packageDir directory of the xdb sources
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerOutside file:line of the first caller outside xdb, tests of xdb are
+// outside
+func callerOutside() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" && (filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go")) {
+			return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package xdb
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogInterceptor(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	sdb := New(db, WithInterceptor(NewSlogInterceptor(logger, SlogOptions{})))
+
+	if _, err := sdb.NewQuery().Select("1").Value(); err != nil {
+		t.Fatal(err)
+	}
+	line := buffer.String()
+	for _, expect := range []string{"level=DEBUG", `msg="xdb query"`, "op=query", "statement=select", "duration=", "caller=slog_test.go:"} {
+		if !strings.Contains(line, expect) {
+			t.Fatalf("expect %s in %s", expect, line)
+		}
+	}
+
+	buffer.Reset()
+	if _, err := sdb.NewQuery().Update("no_such_table").Set("name = ?").Args("a").Exec(); err == nil {
+		t.Fatal("expect error")
+	}
+	if line := buffer.String(); !strings.Contains(line, "level=ERROR") || !strings.Contains(line, "error=") || strings.Contains(line, "args=") {
+		t.Fatal("unexpected error log", line)
+	}
+
+	buffer.Reset()
+	sdb = New(db, WithInterceptor(NewSlogInterceptor(logger, SlogOptions{SlowThreshold: time.Nanosecond, Args: true})))
+	if _, err := sdb.NewQuery().Select("?").Args(1).Value(); err != nil {
+		t.Fatal(err)
+	}
+	if line := buffer.String(); !strings.Contains(line, "level=WARN") || !strings.Contains(line, "args=[1]") {
+		t.Fatal("unexpected slow log", line)
+	}
+}
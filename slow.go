@@ -0,0 +1,218 @@
+package xdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlowQuery statement over the slow query threshold
+type SlowQuery struct {
+	Op Op
+	// SQL normalized, literals and placeholders replaced by ? and spaces
+	// collapsed
+	SQL string
+	// Args bound, their types only when redacted
+	Args     []interface{}
+	Duration time.Duration
+	// Caller file:line of the call site outside xdb
+	Caller string
+	Time   time.Time
+	Err    error
+}
+
+// SlowQuerySink receive the slow queries of a DB
+type SlowQuerySink interface {
+	Record(ctx context.Context, q SlowQuery)
+}
+
+// SlowQuerySinkFunc SlowQuerySink of a func
+type SlowQuerySinkFunc func(ctx context.Context, q SlowQuery)
+
+// Record call f
+func (f SlowQuerySinkFunc) Record(ctx context.Context, q SlowQuery) {
+	f(ctx, q)
+}
+
+// SlowQueryOptions options of WithSlowQuery
+type SlowQueryOptions struct {
+	// Threshold statements taking Threshold or more are slow
+	Threshold time.Duration
+	// RedactArgs record the types of the args instead of their values
+	RedactArgs bool
+	// Sink receive the slow queries, eg. a SlowQueryLog
+	Sink SlowQuerySink
+}
+
+// WithSlowQuery report the statements of the DB slower than the threshold to
+// the sink
+func WithSlowQuery(opts SlowQueryOptions) Option {
+	return func(cfg *config) {
+		if opts.Threshold > 0 && opts.Sink != nil {
+			cfg.interceptors = append(cfg.interceptors, &slowQueryInterceptor{opts: opts})
+		}
+	}
+}
+
+type slowQueryInterceptor struct {
+	opts SlowQueryOptions
+}
+
+func (s *slowQueryInterceptor) Before(ctx context.Context, e *Event) context.Context {
+	return ctx
+}
+
+func (s *slowQueryInterceptor) After(ctx context.Context, e *Event) {
+	if e.SQL == "" || e.Duration < s.opts.Threshold {
+		return
+	}
+	args := e.Args
+	if s.opts.RedactArgs {
+		args = redactArgs(args)
+	}
+	s.opts.Sink.Record(ctx, SlowQuery{
+		Op:       e.Op,
+		SQL:      normalizeSQL(e.SQL),
+		Args:     args,
+		Duration: e.Duration,
+		Caller:   callerOutside(),
+		Time:     e.Start,
+		Err:      e.Err,
+	})
+}
+
+func redactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg != nil {
+			redacted[i] = fmt.Sprintf("<%T>", arg)
+		}
+	}
+	return redacted
+}
+
+// normalizeSQL replace string and number literals and the placeholders of
+// the dialects by ?, collapse spaces and lists of ? so statements differing
+// only by values are the same
+func normalizeSQL(str string) string {
+	buffer := new(strings.Builder)
+	space := false
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '\'':
+			for i++; i < len(str); i++ {
+				if str[i] == '\'' {
+					if i+1 < len(str) && str[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			c = '?'
+		case (i == 0 || !isIdentByte(str[i-1])) && placeholderLen(str[i:]) > 0:
+			i += placeholderLen(str[i:]) - 1
+			c = '?'
+		case c >= '0' && c <= '9' && (i == 0 || !isIdentByte(str[i-1])):
+			for i+1 < len(str) && (str[i+1] >= '0' && str[i+1] <= '9' || str[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+		if space && buffer.Len() > 0 {
+			buffer.WriteByte(' ')
+		}
+		space = false
+		buffer.WriteByte(c)
+	}
+	return collapseMarks(buffer.String())
+}
+
+// collapseMarks collapse "?, ?, ?" to "?"
+func collapseMarks(str string) string {
+	for {
+		s := strings.ReplaceAll(str, "?, ?", "?")
+		s = strings.ReplaceAll(s, "?,?", "?")
+		if s == str {
+			return s
+		}
+		str = s
+	}
+}
+
+// placeholderLen length of the $1, :1 or @p1 placeholder str starts with, 0
+// when none
+func placeholderLen(str string) int {
+	n := 1
+	switch {
+	case strings.HasPrefix(str, "@p"):
+		n = 2
+	case !strings.HasPrefix(str, "$") && !strings.HasPrefix(str, ":"):
+		return 0
+	}
+	digits := n
+	for digits < len(str) && str[digits] >= '0' && str[digits] <= '9' {
+		digits++
+	}
+	if digits == n {
+		return 0
+	}
+	return digits
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c == ':' || c == '@' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// SlowQueryLog SlowQuerySink keeping the latest slow queries in a ring buffer
+type SlowQueryLog struct {
+	mu      sync.Mutex
+	entries []SlowQuery
+	next    int
+	full    bool
+}
+
+// NewSlowQueryLog log of the latest size slow queries, default 100
+func NewSlowQueryLog(size int) *SlowQueryLog {
+	if size <= 0 {
+		size = 100
+	}
+	return &SlowQueryLog{entries: make([]SlowQuery, size)}
+}
+
+// Record keep q, overwriting the oldest when full
+func (l *SlowQueryLog) Record(ctx context.Context, q SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = q
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Entries the slow queries kept, oldest first
+func (l *SlowQueryLog) Entries() []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]SlowQuery(nil), l.entries[:l.next]...)
+	}
+	return append(append([]SlowQuery(nil), l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// Reset drop the slow queries kept
+func (l *SlowQueryLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.entries {
+		l.entries[i] = SlowQuery{}
+	}
+	l.next, l.full = 0, false
+}
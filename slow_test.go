@@ -0,0 +1,56 @@
+package xdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	cases := []struct {
+		sql    string
+		expect string
+	}{
+		{"select *\n  from user\twhere id = 12 and name = 'a''b'", "select * from user where id = ? and name = ?"},
+		{"select * from t1 where id in (?, ?, ?) limit 10", "select * from t1 where id in (?) limit ?"},
+		{"select * from user where id in ($1, $2) and depart = $3", "select * from user where id in (?) and depart = ?"},
+		{"select * from user where id in ($1, $2, $3) and depart = $4", "select * from user where id in (?) and depart = ?"},
+		{"select * from user where id in (@p1, @p2) and depart = @p3", "select * from user where id in (?) and depart = ?"},
+		{"select * from user where id in (:1, :2, :3) and depart = :name and n = a::int", "select * from user where id in (?) and depart = :name and n = a::int"},
+	}
+	for _, c := range cases {
+		if s := normalizeSQL(c.sql); s != c.expect {
+			t.Fatalf("expect %q, got %q", c.expect, s)
+		}
+	}
+}
+
+func TestSlowQuery(t *testing.T) {
+	slowLog := NewSlowQueryLog(2)
+	sdb := New(db, WithSlowQuery(SlowQueryOptions{Threshold: time.Nanosecond, RedactArgs: true, Sink: slowLog}))
+	for i := 0; i < 3; i++ {
+		if _, err := sdb.NewQuery().Select("'secret' || ?").Args(i).Value(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := slowLog.Entries()
+	if len(entries) != 2 {
+		t.Fatal("expect 2 entries, got", entries)
+	}
+	q := entries[1]
+	if q.SQL != "SELECT ? || ?" || len(q.Args) != 1 || q.Args[0] != "<int>" || q.Duration <= 0 || q.Caller == "" {
+		t.Fatalf("unexpected slow query %+v", q)
+	}
+	slowLog.Reset()
+	if len(slowLog.Entries()) != 0 {
+		t.Fatal("expect empty log")
+	}
+
+	slowLog = NewSlowQueryLog(0)
+	sdb = New(db, WithSlowQuery(SlowQueryOptions{Threshold: time.Hour, Sink: slowLog}))
+	if _, err := sdb.NewQuery().Select("1").Value(); err != nil {
+		t.Fatal(err)
+	}
+	if len(slowLog.Entries()) != 0 {
+		t.Fatal("expect no slow query")
+	}
+}
@@ -0,0 +1,318 @@
+package xdb
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// template directives, rendered against the ReflectArgs value:
+//
+//	{{if Name}} ... {{else}} ... {{end}}   include when Name is non-zero, {{if !Name}} negates
+//	{{range Ids ", "}} ${.} {{end}}       repeat for every element, "." is the element
+//	{{where}} ... {{end}}                 trim dangling and/or, prefix WHERE when not empty
+//	{{set}} ... {{end}}                   trim dangling commas, prefix SET when not empty
+//	{{trim}} ... {{end}}                  trim dangling and/or and commas
+const (
+	tplOpen  = "{{"
+	tplClose = "}}"
+)
+
+type tplNode interface {
+	exec(buffer *bytes.Buffer, data interface{}, scope string) error
+}
+
+type tplText string
+
+type tplIf struct {
+	path string
+	not  bool
+	then []tplNode
+	els  []tplNode
+}
+
+type tplRange struct {
+	path string
+	sep  string
+	body []tplNode
+}
+
+type tplTrim struct {
+	prefix string
+	body   []tplNode
+}
+
+// parseTemplate parse tpl into nodes
+func parseTemplate(tpl string) ([]tplNode, error) {
+	p := &tplParser{str: tpl}
+	nodes, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("xdb template: unexpected {{%s}}", end)
+	}
+	return nodes, nil
+}
+
+type tplParser struct {
+	str string
+}
+
+// parse nodes until {{end}}, {{else}} or eof, return the directive which
+// stopped it
+func (p *tplParser) parse() ([]tplNode, string, error) {
+	var nodes []tplNode
+	for p.str != "" {
+		start := strings.Index(p.str, tplOpen)
+		if start == -1 {
+			nodes = append(nodes, tplText(p.str))
+			p.str = ""
+			break
+		}
+		if start > 0 {
+			nodes = append(nodes, tplText(p.str[:start]))
+		}
+		offset := strings.Index(p.str[start:], tplClose)
+		if offset == -1 {
+			return nil, "", fmt.Errorf("xdb template: unclosed %s", tplOpen)
+		}
+		directive := strings.TrimSpace(p.str[start+len(tplOpen) : start+offset])
+		p.str = p.str[start+offset+len(tplClose):]
+
+		name, arg := directive, ""
+		if i := strings.IndexAny(directive, " \t\n"); i != -1 {
+			name, arg = directive[:i], strings.TrimSpace(directive[i+1:])
+		}
+		switch name {
+		case "end", "else":
+			return nodes, name, nil
+		case "if":
+			node, err := p.parseIf(arg)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		case "range":
+			node, err := p.parseRange(arg)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		case "where", "set", "trim":
+			body, end, err := p.parse()
+			if err != nil {
+				return nil, "", err
+			}
+			if end != "end" {
+				return nil, "", fmt.Errorf("xdb template: {{%s}} without {{end}}", name)
+			}
+			prefix := ""
+			if name != "trim" {
+				prefix = strings.ToUpper(name) + " "
+			}
+			nodes = append(nodes, &tplTrim{prefix: prefix, body: body})
+		default:
+			return nil, "", fmt.Errorf("xdb template: unknown directive {{%s}}", directive)
+		}
+	}
+	return nodes, "", nil
+}
+
+func (p *tplParser) parseIf(arg string) (tplNode, error) {
+	node := &tplIf{path: strings.TrimPrefix(arg, "!"), not: strings.HasPrefix(arg, "!")}
+	if node.path == "" {
+		return nil, fmt.Errorf("xdb template: {{if}} without value")
+	}
+	var end string
+	var err error
+	node.then, end, err = p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end == "else" {
+		node.els, end, err = p.parse()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if end != "end" {
+		return nil, fmt.Errorf("xdb template: {{if %s}} without {{end}}", arg)
+	}
+	return node, nil
+}
+
+func (p *tplParser) parseRange(arg string) (tplNode, error) {
+	node := &tplRange{path: arg}
+	if i := strings.IndexAny(arg, " \t\n"); i != -1 {
+		sep, err := strconv.Unquote(strings.TrimSpace(arg[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("xdb template: {{range %s}} separator must be quoted", arg)
+		}
+		node.path, node.sep = arg[:i], sep
+	}
+	if node.path == "" {
+		return nil, fmt.Errorf("xdb template: {{range}} without value")
+	}
+	body, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != "end" {
+		return nil, fmt.Errorf("xdb template: {{range %s}} without {{end}}", arg)
+	}
+	node.body = body
+	return node, nil
+}
+
+// executeTemplate render nodes against data into sql with ${} tokens, tokens
+// inside {{range}} are rewritten to indexed paths, eg. ${.ID} to ${Items[0].ID}
+func executeTemplate(nodes []tplNode, data interface{}) (string, error) {
+	buffer := new(bytes.Buffer)
+	if err := execNodes(buffer, nodes, data, ""); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func execNodes(buffer *bytes.Buffer, nodes []tplNode, data interface{}, scope string) error {
+	for _, node := range nodes {
+		if err := node.exec(buffer, data, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scopePath resolve path relative to the range scope
+func scopePath(path, scope string) string {
+	switch {
+	case path == ".":
+		return scope
+	case strings.HasPrefix(path, ".["):
+		return scope + path[1:]
+	case strings.HasPrefix(path, "."):
+		return scope + path
+	}
+	return path
+}
+
+func templateValue(data interface{}, path string) (interface{}, bool) {
+	if data == nil || path == "" {
+		return nil, false
+	}
+	return reflectGetValue(data, path)
+}
+
+func (t tplText) exec(buffer *bytes.Buffer, data interface{}, scope string) error {
+	str := string(t)
+	if scope == "" {
+		buffer.WriteString(str)
+		return nil
+	}
+	for {
+		start := strings.Index(str, "${.")
+		if start == -1 {
+			break
+		}
+		offset := strings.Index(str[start:], "}")
+		if offset == -1 || (start > 0 && str[start-1] == '\\') {
+			buffer.WriteString(str[:start+3])
+			str = str[start+3:]
+			continue
+		}
+		buffer.WriteString(str[:start])
+		buffer.WriteString("${")
+		buffer.WriteString(scopePath(str[start+2:start+offset], scope))
+		buffer.WriteString("}")
+		str = str[start+offset+1:]
+	}
+	buffer.WriteString(str)
+	return nil
+}
+
+func (t *tplIf) exec(buffer *bytes.Buffer, data interface{}, scope string) error {
+	val, ok := templateValue(data, scopePath(t.path, scope))
+	if (ok && !isZero(val)) != t.not {
+		return execNodes(buffer, t.then, data, scope)
+	}
+	return execNodes(buffer, t.els, data, scope)
+}
+
+func (t *tplRange) exec(buffer *bytes.Buffer, data interface{}, scope string) error {
+	path := scopePath(t.path, scope)
+	val, ok := templateValue(data, path)
+	if !ok || val == nil {
+		return nil
+	}
+	value := reflectIndirect(reflect.ValueOf(val))
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+	case reflect.Invalid:
+		return nil
+	default:
+		return fmt.Errorf("xdb template: can't range over %s (%s)", path, value.Kind())
+	}
+	for i := 0; i < value.Len(); i++ {
+		if i > 0 {
+			buffer.WriteString(t.sep)
+		}
+		if err := execNodes(buffer, t.body, data, path+"["+strconv.Itoa(i)+"]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tplTrim) exec(buffer *bytes.Buffer, data interface{}, scope string) error {
+	body := new(bytes.Buffer)
+	if err := execNodes(body, t.body, data, scope); err != nil {
+		return err
+	}
+	str := trimSQL(body.String())
+	if str != "" {
+		buffer.WriteString(t.prefix)
+		buffer.WriteString(str)
+	}
+	return nil
+}
+
+// trimSQL trim spaces, commas and and/or keywords dangling at both ends
+func trimSQL(str string) string {
+	for {
+		s := strings.Trim(strings.TrimSpace(str), ",")
+		for _, word := range []string{"and", "or"} {
+			if len(s) >= len(word) && strings.EqualFold(s[:len(word)], word) && (len(s) == len(word) || isSQLSpace(s[len(word)])) {
+				s = s[len(word):]
+			}
+			if i := len(s) - len(word); i > 0 && strings.EqualFold(s[i:], word) && isSQLSpace(s[i-1]) {
+				s = s[:i]
+			}
+		}
+		if s == str {
+			return s
+		}
+		str = s
+	}
+}
+
+func isSQLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '(' || c == ')'
+}
+
+// isZero report whether val is nil, zero or an empty slice, map or string
+func isZero(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	value := reflect.ValueOf(val)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return value.IsZero()
+}
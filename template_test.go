@@ -0,0 +1,95 @@
+package xdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	tpl := `select * from user
+{{where}}
+	{{if Name}} and username = ${Name} {{end}}
+	{{if Ids}} and id in ({{range Ids ", "}}${.}{{end}}) {{end}}
+	{{if Depart}} or departname = ${Depart} {{else}} and departname is not null {{end}}
+{{end}}`
+
+	type Filter struct {
+		Name   string
+		Depart *string
+		Ids    []int64
+	}
+
+	depart := "dev"
+	cases := []struct {
+		filter Filter
+		expect string
+		args   int
+		rows   int
+	}{
+		{Filter{}, "select * from user WHERE departname is not null", 0, 5},
+		{Filter{Name: "mingo-1"}, "select * from user WHERE username = ? and departname is not null", 1, 1},
+		{Filter{Ids: []int64{1, 2}, Depart: &depart}, "select * from user WHERE id in (?, ?) or departname = ?", 3, 5},
+	}
+	for _, c := range cases {
+		q := ndb.NewQuery().Template(tpl).ReflectArgs(c.filter).(*query)
+		rawSQL, args, err := q.bind()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rawSQL = strings.Join(strings.Fields(rawSQL), " "); rawSQL != c.expect || len(args) != c.args {
+			t.Fatalf("expect %q, got %q %v", c.expect, rawSQL, args)
+		}
+		rows, err := q.Rows()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != c.rows {
+			t.Fatalf("expect %d rows, got %d", c.rows, len(rows))
+		}
+	}
+
+	q := newQuery(nil, newConfig(nil))
+	q.Template("update user {{set}} {{if Name}} username = ${Name}, {{end}} {{if Depart}} departname = ${Depart}, {{end}} {{end}} where id = ${Id}")
+	q.ReflectArgs(map[string]interface{}{"Name": "x", "Id": 1})
+	rawSQL, args, err := q.bind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "update user SET username = ? where id = ?"; rawSQL != expect || len(args) != 2 {
+		t.Fatalf("expect %q, got %q %v", expect, rawSQL, args)
+	}
+
+	q = newQuery(nil, newConfig([]Option{WithDialect(Postgres)}))
+	q.Template("insert into user (username, departname) values {{range Users \", \"}}(${.Name}, {{if .Depart}}${.Depart}{{else}}'none'{{end}}){{end}}")
+	q.ReflectArgs(map[string]interface{}{"Users": []map[string]string{{"Name": "a", "Depart": "dev"}, {"Name": "b"}}})
+	rawSQL, args, err = q.bind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "insert into user (username, departname) values ($1, $2), ($3, 'none')"; rawSQL != expect || fmt.Sprint(args) != "[a dev b]" {
+		t.Fatalf("expect %q, got %q %v", expect, rawSQL, args)
+	}
+
+	for _, tpl := range []string{"{{if A}}", "{{end}}", "{{foo}}", "{{range A ,}}{{end}}", "{{if A}"} {
+		if _, err := parseTemplate(tpl); err == nil {
+			t.Fatal("expect error for", tpl)
+		}
+	}
+}
+
+func TestTrimSQL(t *testing.T) {
+	cases := map[string]string{
+		" and a = 1 ":          "a = 1",
+		"OR a = 1 and ":        "a = 1",
+		"a = 1, b = 2, ":       "a = 1, b = 2",
+		" , and ":              "",
+		"brand = 1 and(b = 1)": "brand = 1 and(b = 1)",
+		"order = 1":            "order = 1",
+	}
+	for str, expect := range cases {
+		if s := trimSQL(str); s != expect {
+			t.Fatalf("%q: expect %q, got %q", str, expect, s)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package xdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracer start spans, eg. a bridge to OpenTelemetry
+type Tracer interface {
+	// Start a span child of the span in ctx, return ctx with the span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span of a Tracer
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// WithTracer open a span for every exec, query and prepare of the DB, and one
+// for every TX from Begin to Commit or Rollback, parent of the spans of its
+// statements. The span of a query ends when its rows are read to the end or
+// closed
+func WithTracer(tracer Tracer) Option {
+	return func(cfg *config) {
+		if tracer != nil {
+			cfg.interceptors = append(cfg.interceptors, &traceInterceptor{tracer: tracer, cfg: cfg})
+		}
+	}
+}
+
+type (
+	spanKey   struct{}
+	txSpanKey struct{}
+)
+
+type traceInterceptor struct {
+	tracer Tracer
+	cfg    *config
+}
+
+// isTxEvent report whether e is the begin, commit or rollback of a TX, not of
+// a savepoint
+func isTxEvent(e *Event) bool {
+	return e.Savepoint == "" && (e.Op == OpBegin || e.Op == OpCommit || e.Op == OpRollback)
+}
+
+func (t *traceInterceptor) Before(ctx context.Context, e *Event) context.Context {
+	switch {
+	case isTxEvent(e) && e.Op == OpBegin:
+		ctx, span := t.tracer.Start(ctx, "xdb.tx")
+		span.SetAttribute("db.system", t.cfg.dialect.Name())
+		return context.WithValue(ctx, txSpanKey{}, span)
+	case isTxEvent(e):
+		return ctx
+	}
+	ctx, span := t.tracer.Start(ctx, "xdb."+string(e.Op))
+	span.SetAttribute("db.system", t.cfg.dialect.Name())
+	span.SetAttribute("db.statement", e.SQL)
+	span.SetAttribute("db.operation", e.Statement)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *traceInterceptor) After(ctx context.Context, e *Event) {
+	if isTxEvent(e) {
+		span, ok := ctx.Value(txSpanKey{}).(Span)
+		if !ok {
+			return
+		}
+		if e.Err != nil {
+			span.RecordError(e.Err)
+		}
+		switch {
+		case e.Op != OpBegin:
+			span.SetAttribute("db.transaction", string(e.Op))
+			span.End()
+		case e.Err != nil:
+			span.End()
+		}
+		return
+	}
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	if e.RowsAffected >= 0 {
+		span.SetAttribute("db.rows_affected", e.RowsAffected)
+	}
+	if e.RowsRead >= 0 {
+		span.SetAttribute("db.rows_read", e.RowsRead)
+	}
+	if e.Attempt > 1 {
+		span.SetAttribute("db.attempt", e.Attempt)
+	}
+	if e.Err != nil {
+		span.RecordError(e.Err)
+	}
+	span.End()
+}
+
+// RecordingTracer Tracer keeping the spans in memory, for tests
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan Span of a RecordingTracer
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Err        error
+	StartTime  time.Time
+	EndTime    time.Time
+
+	mu *sync.Mutex
+}
+
+type recordedSpanKey struct{}
+
+// NewRecordingTracer new recording tracer
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+// Start record a span, child of the recorded span in ctx
+func (t *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: map[string]interface{}{},
+		StartTime:  time.Now(),
+		mu:         &t.mu,
+	}
+	span.Parent, _ = ctx.Value(recordedSpanKey{}).(*RecordedSpan)
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans the spans started, in start order
+func (t *RecordingTracer) Spans() []*RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*RecordedSpan(nil), t.spans...)
+}
+
+// Reset drop the spans
+func (t *RecordingTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+// SetAttribute set the attribute key
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+// RecordError keep err
+func (s *RecordedSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Err = err
+}
+
+// End set EndTime, once
+func (s *RecordedSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.EndTime.IsZero() {
+		s.EndTime = time.Now()
+	}
+}
+
+// Ended report whether End was called
+func (s *RecordedSpan) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.EndTime.IsZero()
+}
@@ -0,0 +1,121 @@
+package xdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	tracer := NewRecordingTracer()
+	tdb := New(db, WithDialect(SQLite), WithTracer(tracer))
+	if _, err := tdb.NewQuery().SQL("CREATE TABLE `trace` (`id` INTEGER PRIMARY KEY)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer tdb.NewQuery().SQL("DROP TABLE `trace`").Exec()
+	tracer.Reset()
+
+	errFail := errors.New("fail")
+	err := tdb.Transact(context.Background(), nil, func(tx TX) error {
+		if _, err := tx.NewQuery().InsertInto("trace").Columns("id").Values("?").Args(1).Exec(); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if err != errFail {
+		t.Fatal("expect fail, got", err)
+	}
+	if _, err := tdb.NewQuery().Select("id").From("trace").Rows(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 3 {
+		t.Fatal("expect 3 spans, got", len(spans))
+	}
+	tx, insert, sel := spans[0], spans[1], spans[2]
+	if tx.Name != "xdb.tx" || !tx.Ended() || tx.Attributes["db.transaction"] != "rollback" || tx.Attributes["db.system"] != "sqlite" {
+		t.Fatalf("unexpected tx span %+v", tx)
+	}
+	if insert.Name != "xdb.exec" || insert.Parent != tx || !insert.Ended() || insert.Attributes["db.operation"] != "insert" || insert.Attributes["db.rows_affected"] != int64(1) {
+		t.Fatalf("unexpected insert span %+v", insert)
+	}
+	if sel.Name != "xdb.query" || sel.Parent != nil || sel.Attributes["db.statement"] != "SELECT id\nFROM trace" || sel.Attributes["db.rows_read"] != int64(0) {
+		t.Fatalf("unexpected select span %+v", sel)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if _, err := tdb.NewQuery().InsertInto("trace").Columns("id").Values("?").Args(i).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tracer.Reset()
+	it, err := tdb.NewQuery().Select("id").From("trace").Iterate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	it.Next()
+	it.Next()
+	span := tracer.Spans()[0]
+	if span.Ended() {
+		t.Fatal("expect the query span open until the rows are closed")
+	}
+	it.Close()
+	it.Close()
+	if !span.Ended() || span.Attributes["db.rows_read"] != int64(2) {
+		t.Fatalf("unexpected query span %+v", span)
+	}
+
+	tracer.Reset()
+	if it, err = tdb.NewQuery().Select("id").From("trace").Iterate(); err != nil {
+		t.Fatal(err)
+	}
+	for it.Next() {
+	}
+	span = tracer.Spans()[0]
+	if !span.Ended() || span.Attributes["db.rows_read"] != int64(3) {
+		t.Fatalf("expect the span ended when the rows are read, got %+v", span)
+	}
+	it.Close()
+}
+
+// sqlserverSavepoint SQLServer, with the savepoints of sqlite to run on the
+// test db, its release is still empty
+type sqlserverSavepoint struct {
+	Dialect
+}
+
+func (sqlserverSavepoint) Savepoint(name string) string { return SQLite.Savepoint(name) }
+func (sqlserverSavepoint) RollbackSavepoint(name string) string {
+	return SQLite.RollbackSavepoint(name)
+}
+
+func TestTracerNestedCommit(t *testing.T) {
+	tracer := NewRecordingTracer()
+	tdb := New(db, WithDialect(sqlserverSavepoint{SQLServer}), WithTracer(tracer))
+	tx, err := tdb.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	txSpan := tracer.Spans()[0]
+	if txSpan.Name != "xdb.tx" || txSpan.Ended() {
+		t.Fatalf("expect the tx span open after the nested commit, got %+v", txSpan)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if !txSpan.Ended() || txSpan.Attributes["db.transaction"] != "commit" || txSpan.Attributes["db.system"] != "sqlserver" {
+		t.Fatalf("unexpected tx span %+v", txSpan)
+	}
+	spans := tracer.Spans()
+	if len(spans) != 3 || spans[2].Name != "xdb.commit" || spans[2].Parent != txSpan {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+}
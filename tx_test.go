@@ -0,0 +1,122 @@
+package xdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestTransact(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `account` (`id` INTEGER PRIMARY KEY, `balance` INTEGER NOT NULL)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `account`").Exec()
+
+	balance := func() int64 {
+		row, err := ndb.NewQuery().Select("coalesce(sum(balance), 0) as n").From("account").Row()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return row.Get("n").Int()
+	}
+	insert := func(tx TX) error {
+		_, err := tx.NewQuery().InsertInto("account").Columns("balance").Values("?").Args(10).Exec()
+		return err
+	}
+
+	if err := ndb.Transact(context.Background(), nil, insert); err != nil {
+		t.Fatal(err)
+	}
+	if n := balance(); n != 10 {
+		t.Fatal("commit failed", n)
+	}
+
+	errFail := errors.New("fail")
+	err := ndb.Transact(context.Background(), nil, func(tx TX) error {
+		if err := insert(tx); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if err != errFail || balance() != 10 {
+		t.Fatal("rollback on error failed", err, balance())
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatal("expect panic", p)
+			}
+		}()
+		ndb.Transact(context.Background(), nil, func(tx TX) error {
+			insert(tx)
+			panic("boom")
+		})
+	}()
+	if n := balance(); n != 10 {
+		t.Fatal("rollback on panic failed", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ndb.Transact(ctx, &sql.TxOptions{ReadOnly: true}, insert); err == nil {
+		t.Fatal("expect canceled context error")
+	}
+}
+
+func TestNestedTX(t *testing.T) {
+	if _, err := ndb.NewQuery().SQL("CREATE TABLE `nested` (`id` INTEGER PRIMARY KEY)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.NewQuery().SQL("DROP TABLE `nested`").Exec()
+
+	insert := func(tx TX, id int) {
+		if _, err := tx.NewQuery().InsertInto("nested").Columns("id").Values("?").Args(id).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := ndb.Transact(context.Background(), nil, func(tx TX) error {
+		insert(tx, 1)
+
+		child, err := tx.Begin()
+		if err != nil {
+			return err
+		}
+		insert(child, 2)
+		grandchild, err := child.Begin()
+		if err != nil {
+			return err
+		}
+		insert(grandchild, 3)
+		if err := grandchild.Commit(); err != nil {
+			return err
+		}
+		if err := child.Rollback(); err != nil {
+			return err
+		}
+		if err := child.Commit(); err != sql.ErrTxDone {
+			t.Fatal("expect ErrTxDone, got", err)
+		}
+
+		child, err = tx.Begin()
+		if err != nil {
+			return err
+		}
+		defer child.Rollback()
+		insert(child, 4)
+		return child.Commit()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := Pluck[int64](ndb.NewQuery().Select("id").From("nested").OrderBy("id"), "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 4 {
+		t.Fatal("unexpected ids", ids)
+	}
+}
@@ -1,19 +1,51 @@
 package xdb
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 )
 
-// Value colment
+// Value colment, nil for NULL
 type Value sql.RawBytes
 
+var (
+	// ErrNullValue accessor of NULL value
+	ErrNullValue = errors.New("xdb: value is null")
+	// ErrColumnNotFound row has no such column
+	ErrColumnNotFound = errors.New("xdb: column not found")
+)
+
+// IsNull return value is NULL
+func (v Value) IsNull() bool {
+	return v == nil
+}
+
 // Int value to int64
 func (v Value) Int() int64 {
 	i, _ := strconv.ParseInt(string(v), 10, 64)
 	return i
 }
 
+// IntE value to int64, ErrNullValue for NULL
+func (v Value) IntE() (int64, error) {
+	if v == nil {
+		return 0, ErrNullValue
+	}
+	return strconv.ParseInt(string(v), 10, 64)
+}
+
+// UintE value to uint64, ErrNullValue for NULL
+func (v Value) UintE() (uint64, error) {
+	if v == nil {
+		return 0, ErrNullValue
+	}
+	return strconv.ParseUint(string(v), 10, 64)
+}
+
 func (v Value) String() string {
 	return string(v)
 }
@@ -24,20 +56,107 @@ func (v Value) Float() float64 {
 	return f
 }
 
+// FloatE value to float64, ErrNullValue for NULL
+func (v Value) FloatE() (float64, error) {
+	if v == nil {
+		return 0, ErrNullValue
+	}
+	return strconv.ParseFloat(string(v), 64)
+}
+
 // Bool value to bool
 func (v Value) Bool() bool {
 	b, _ := strconv.ParseBool(string(v))
 	return b
 }
 
+// BoolE value to bool, ErrNullValue for NULL
+func (v Value) BoolE() (bool, error) {
+	if v == nil {
+		return false, ErrNullValue
+	}
+	return strconv.ParseBool(string(v))
+}
+
+// TimeE value to time.Time, ErrNullValue for NULL
+func (v Value) TimeE() (time.Time, error) {
+	if v == nil {
+		return time.Time{}, ErrNullValue
+	}
+	return parseTime(string(v))
+}
+
+// BytesE copy of value bytes, ErrNullValue for NULL
+func (v Value) BytesE() ([]byte, error) {
+	if v == nil {
+		return nil, ErrNullValue
+	}
+	b := make([]byte, len(v))
+	copy(b, v)
+	return b, nil
+}
+
+// NullInt64 value to sql.NullInt64, invalid for NULL or unparsable value
+func (v Value) NullInt64() sql.NullInt64 {
+	i, err := v.IntE()
+	return sql.NullInt64{Int64: i, Valid: err == nil}
+}
+
+// NullFloat64 value to sql.NullFloat64, invalid for NULL or unparsable value
+func (v Value) NullFloat64() sql.NullFloat64 {
+	f, err := v.FloatE()
+	return sql.NullFloat64{Float64: f, Valid: err == nil}
+}
+
+// NullBool value to sql.NullBool, invalid for NULL or unparsable value
+func (v Value) NullBool() sql.NullBool {
+	b, err := v.BoolE()
+	return sql.NullBool{Bool: b, Valid: err == nil}
+}
+
+// NullString value to sql.NullString, invalid for NULL
+func (v Value) NullString() sql.NullString {
+	return sql.NullString{String: string(v), Valid: v != nil}
+}
+
+// NullTime value to sql.NullTime, invalid for NULL or unparsable value
+func (v Value) NullTime() sql.NullTime {
+	t, err := v.TimeE()
+	return sql.NullTime{Time: t, Valid: err == nil}
+}
+
+// parseTime parse the text form of datetime and date columns
+func parseTime(str string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return t, nil
+	}
+	if len(str) >= 19 {
+		return time.Parse("2006-01-02 15:04:05", str[:19])
+	}
+	if len(str) >= 10 {
+		return time.Parse("2006-01-02", str[:10])
+	}
+	return time.Time{}, fmt.Errorf("xdb: can't parse %q as time", str)
+}
+
 // Row row value
 type Row map[string]Value
 
-// Get get value of key
+// Get get value of key, nil for both NULL and missing column
 func (r Row) Get(key string) Value {
 	return r[key]
 }
 
+// GetE get value of key, ErrColumnNotFound for missing column, use
+// Value.IsNull for NULL
+func (r Row) GetE(key string) (Value, error) {
+	v, ok := r[key]
+	if !ok {
+		return nil, ErrColumnNotFound
+	}
+	return v, nil
+}
+
 // Set set value of key
 func (r Row) Set(key string, v Value) {
 	r[key] = v
@@ -60,6 +179,12 @@ type Query interface {
 	InsertInto(table string) Query
 	Columns(columns string) Query
 	Values(values string) Query
+	// ValuesRow append a row to VALUES (...), (...), the Values calls are
+	// joined into the first row
+	ValuesRow(values string) Query
+	OnConflict(columns string) Query
+	DoUpdate(columns string) Query
+	DoNothing() Query
 	Select(columns string) Query
 	SelectDistinct(columns string) Query
 	From(tables string) Query
@@ -72,12 +197,16 @@ type Query interface {
 	Having(having string) Query
 	And() Query
 	Or() Query
-	GroupBy(having string) Query
+	GroupBy(groupBy string) Query
 	OrderBy(orderBy string) Query
 	Limit(limit string) Query
 	SQL(sqlString string) Query
+	Template(tpl string) Query
 	String() string
 
+	WithContext(ctx context.Context) Query
+	Context() context.Context
+
 	Prepare() error
 	Close() error
 
@@ -91,26 +220,48 @@ type Query interface {
 	Rows() ([]Row, error)
 	ReflectRow(row interface{}) error
 	ReflectRows(rows interface{}) (int64, error)
+
+	Each(fn func(Row) error) error
+	Iterate() (Iterator, error)
+	Paginate(page, size int64, rows interface{}) (*Page, error)
+	Seek(keys string, cursor string, size int64, rows interface{}) (*KeysetPage, error)
 }
 
-// LogFunc func print sql log
+// Iterator stream rows without loading the whole result, Close must be
+// called when the iteration stops early
+type Iterator interface {
+	Next() bool
+	Columns() []string
+	Scan(dest ...interface{}) error
+	Row() (Row, error)
+	ScanStruct(dest interface{}) error
+	Err() error
+	Close() error
+}
+
+// LogFunc func print sql log, shared by all DB, WithInterceptor hooks per DB
+// with the duration, rows affected and error
 var LogFunc func(sql string, args ...interface{})
 
 // Helper qeury helper
 type Helper interface {
 	NewQuery() Query
 	Querier() Querier
+	Dialect() Dialect
 }
 
 // DB db
 type DB interface {
 	Helper
 	Begin() (TX, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (TX, error)
+	Transact(ctx context.Context, opts *sql.TxOptions, fn func(TX) error) error
 }
 
 // TX tx
 type TX interface {
 	Helper
+	Begin() (TX, error)
 	Rollback() error
 	Commit() error
 }
@@ -122,6 +273,11 @@ type Querier interface {
 	QueryRow(query string, args ...interface{}) *sql.Row
 
 	Prepare(query string) (*sql.Stmt, error)
+
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
 }
 
 const tagName = "db"
@@ -1,6 +1,10 @@
 package xdb
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
 
 func log(sql string, args ...interface{}) {
 	if LogFunc != nil {
@@ -8,54 +12,208 @@ func log(sql string, args ...interface{}) {
 	}
 }
 
+// Option db option
+type Option func(*config)
+
+type config struct {
+	dialect      Dialect
+	retry        *RetryPolicy
+	interceptors []Interceptor
+	comment      *CommentOptions
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{dialect: defaultDialect}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithDialect set sql dialect, default "?" placeholders
+func WithDialect(dialect Dialect) Option {
+	return func(cfg *config) {
+		if dialect != nil {
+			cfg.dialect = dialect
+		}
+	}
+}
+
 type xdb struct {
-	db *sql.DB
+	db  *sql.DB
+	cfg *config
 }
 
 type xtx struct {
-	tx *sql.Tx
+	tx  *sql.Tx
+	cfg *config
+	ctx context.Context
+	// seq savepoint counter shared by the nested TX
+	seq *int
+	// sp savepoint of a nested TX, nil for the outermost
+	sp *savepoint
+}
+
+type savepoint struct {
+	name string
+	done bool
+}
+
+func newTX(tx *sql.Tx, cfg *config, ctx context.Context) *xtx {
+	return &xtx{tx: tx, cfg: cfg, ctx: ctx, seq: new(int)}
 }
 
 // New db
-func New(db *sql.DB) DB {
+func New(db *sql.DB, opts ...Option) DB {
 	return &xdb{
-		db: db,
+		db:  db,
+		cfg: newConfig(opts),
 	}
 }
 
 func (x xdb) Begin() (TX, error) {
-	tx, err := x.db.Begin()
+	return x.begin(nil, nil)
+}
+
+func (x xdb) BeginTx(ctx context.Context, opts *sql.TxOptions) (TX, error) {
+	return x.begin(ctx, opts)
+}
+
+func (x xdb) begin(ctx context.Context, opts *sql.TxOptions) (TX, error) {
+	var tx *sql.Tx
+	err := intercept(orBackground(ctx), x.cfg, &Event{Op: OpBegin}, func(c context.Context) error {
+		var err error
+		tx, err = x.db.BeginTx(c, opts)
+		// the TX runs with the context of the interceptors, eg. its span
+		ctx = c
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return NewTX(tx), nil
+	return newTX(tx, x.cfg, ctx), nil
+}
+
+// Transact run fn in a transaction begun with opts, commit when fn returns
+// nil, rollback when fn returns an error or panics, the panic goes on after
+// the rollback. queries of the TX run with ctx. with WithRetry the whole
+// transaction runs again on a retryable error
+func (x xdb) Transact(ctx context.Context, opts *sql.TxOptions, fn func(TX) error) error {
+	return retry(ctx, x.cfg, func(ctx context.Context) error {
+		return x.transact(ctx, opts, fn)
+	})
+}
+
+func (x xdb) transact(ctx context.Context, opts *sql.TxOptions, fn func(TX) error) error {
+	tx, err := x.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	done := false
+	defer func() {
+		if !done {
+			tx.Rollback()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	done = true
+	return tx.Commit()
 }
 
 func (x xdb) NewQuery() Query {
-	return &query{querier: x.Querier()}
+	q := newQuery(x.Querier(), x.cfg)
+	q.retry = true
+	return q
 }
 
 func (x xdb) Querier() Querier {
 	return x.db
 }
 
+func (x xdb) Dialect() Dialect {
+	return x.cfg.dialect
+}
+
 // NewTX new transaction
-func NewTX(tx *sql.Tx) TX {
-	return &xtx{tx: tx}
+func NewTX(tx *sql.Tx, opts ...Option) TX {
+	return newTX(tx, newConfig(opts), nil)
 }
 
 func (x xtx) NewQuery() Query {
-	return &query{querier: x.Querier()}
+	q := newQuery(x.Querier(), x.cfg)
+	q.ctx = x.ctx
+	return q
+}
+
+// Begin nested transaction on a savepoint, its Commit releases the savepoint
+// and its Rollback rolls back to it
+func (x xtx) Begin() (TX, error) {
+	*x.seq++
+	sp := &savepoint{name: "xdb_sp_" + strconv.Itoa(*x.seq)}
+	if err := x.execSavepoint(OpBegin, sp.name, x.cfg.dialect.Savepoint(sp.name)); err != nil {
+		return nil, err
+	}
+	child := x
+	child.sp = sp
+	return &child, nil
 }
 
 func (x xtx) Rollback() error {
-	return x.tx.Rollback()
+	if x.sp == nil {
+		return intercept(x.context(), x.cfg, &Event{Op: OpRollback}, func(context.Context) error {
+			return x.tx.Rollback()
+		})
+	}
+	if x.sp.done {
+		return sql.ErrTxDone
+	}
+	x.sp.done = true
+	return x.execSavepoint(OpRollback, x.sp.name, x.cfg.dialect.RollbackSavepoint(x.sp.name))
 }
 
 func (x xtx) Commit() error {
-	return x.tx.Commit()
+	if x.sp == nil {
+		return intercept(x.context(), x.cfg, &Event{Op: OpCommit}, func(context.Context) error {
+			return x.tx.Commit()
+		})
+	}
+	if x.sp.done {
+		return sql.ErrTxDone
+	}
+	x.sp.done = true
+	return x.execSavepoint(OpCommit, x.sp.name, x.cfg.dialect.ReleaseSavepoint(x.sp.name))
+}
+
+// execSavepoint run the statement query of the savepoint name as the op of
+// the nested TX, an empty query is not supported by the dialect and skipped
+func (x xtx) execSavepoint(op Op, name, query string) error {
+	return intercept(x.context(), x.cfg, &Event{Op: op, SQL: query, Savepoint: name}, func(ctx context.Context) error {
+		if query == "" {
+			return nil
+		}
+		log(query)
+		_, err := x.tx.ExecContext(ctx, query)
+		return err
+	})
+}
+
+func (x xtx) context() context.Context {
+	return orBackground(x.ctx)
+}
+
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
 }
 
 func (x xtx) Querier() Querier {
 	return x.tx
 }
+
+func (x xtx) Dialect() Dialect {
+	return x.cfg.dialect
+}